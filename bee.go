@@ -25,21 +25,28 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble"
+	json "github.com/json-iterator/go"
 	"github.com/panjf2000/ants/v2"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 
 	bexecutor "github.com/olive-io/bee/executor"
 	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/history"
 	inv "github.com/olive-io/bee/inventory"
 	"github.com/olive-io/bee/module"
 	mmg "github.com/olive-io/bee/module/manager"
 	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
 	"github.com/olive-io/bee/secret"
+	"github.com/olive-io/bee/tengo"
 	"github.com/olive-io/bee/vars"
 )
 
@@ -47,6 +54,20 @@ const (
 	syncFlag = "sync"
 )
 
+const (
+	// DefaultFlushTimeout is the timeout of flushing the buffered events of callbacks in Runtime.Stop
+	DefaultFlushTimeout = time.Second * 30
+)
+
+const (
+	// NoCheckModeReason is the skip reason of modules which don't support check mode
+	NoCheckModeReason = "skipped: no check mode"
+)
+
+// errRetryable marks the errors of broken connection before the command of module starts,
+// the command runs again by a new connection
+var errRetryable = errors.New("retryable connection error")
+
 type Runtime struct {
 	opts *Options
 
@@ -60,6 +81,21 @@ type Runtime struct {
 	passwords *secret.PasswordManager
 	modules   *mmg.Manager
 	executor  *bexecutor.Executor
+	history   *history.Store
+
+	fmu sync.Mutex
+	// flushers are the callbacks of runs which buffer the events, they are flushed by Stop
+	flushers map[callback.IFlusher]struct{}
+	// closers are the callbacks of runs which hold the resources, they are closed by Stop
+	closers map[callback.ICloser]struct{}
+
+	smu sync.Mutex
+	// synced are the connections which the toolchain is checked by, key is <user>@<host>
+	synced map[string]client.IClient
+
+	pmu sync.Mutex
+	// platforms are the detected platforms of hosts, see hostPlatform
+	platforms map[string]*hostPlatform
 }
 
 func NewRuntime(
@@ -92,7 +128,17 @@ func NewRuntime(
 	}
 
 	passwords := secret.NewPasswordManager(lg, db)
-	executor := bexecutor.NewExecutor(lg, inventory, passwords)
+	knownHosts := options.knownHosts
+	if len(knownHosts) == 0 {
+		knownHosts = []string{filepath.Join(options.dir, "known_hosts")}
+		if home, err := os.UserHomeDir(); err == nil {
+			knownHosts = append(knownHosts, filepath.Join(home, ".ssh", "known_hosts"))
+		}
+	}
+	executor := bexecutor.NewExecutor(lg, inventory, passwords,
+		bexecutor.WithIdleTTL(options.idleTTL),
+		bexecutor.WithHostKeyChecking(options.hostKeyChecking),
+		bexecutor.WithKnownHosts(knownHosts...))
 	modules, err := mmg.NewModuleManager(lg, options.dir)
 	if err != nil {
 		return nil, err
@@ -107,7 +153,12 @@ func NewRuntime(
 		loader:    loader,
 		passwords: passwords,
 		executor:  executor,
+		history:   history.NewStore(lg, db),
 		modules:   modules,
+		flushers:  map[callback.IFlusher]struct{}{},
+		closers:   map[callback.ICloser]struct{}{},
+		synced:    map[string]client.IClient{},
+		platforms: map[string]*hostPlatform{},
 	}
 
 	return rt, nil
@@ -141,7 +192,16 @@ func (rt *Runtime) FindModule(name string) (*module.Module, bool) {
 	return rt.modules.Find(name)
 }
 
+// Execute runs the command of module on the host, the run is recorded in History
 func (rt *Runtime) Execute(ctx context.Context, host, shell string, opts ...RunOption) ([]byte, error) {
+	startAt := time.Now()
+	data, err := rt.execute(ctx, host, shell, opts...)
+	rt.recordExecute(host, shell, startAt, data, err, opts...)
+	return data, err
+}
+
+// execute runs the command of module on the host by the pool
+func (rt *Runtime) execute(ctx context.Context, host, shell string, opts ...RunOption) ([]byte, error) {
 	ech := make(chan error, 1)
 	ch := make(chan []byte, 1)
 	defer func() {
@@ -149,6 +209,13 @@ func (rt *Runtime) Execute(ctx context.Context, host, shell string, opts ...RunO
 		close(ech)
 	}()
 
+	options := newRunOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	rt.trackCallback(options.Callback)
+	copts := []bexecutor.ClientOption{bexecutor.WithUser(options.RemoteUser)}
+
 	err := rt.pool.Submit(func() {
 		call := func() (data []byte, err error) {
 			defer func() {
@@ -157,17 +224,31 @@ func (rt *Runtime) Execute(ctx context.Context, host, shell string, opts ...RunO
 					err = fmt.Errorf("%v at %s:%d", re, file, line)
 				}
 			}()
-			conn, err := rt.executor.GetClient(host)
-			if err != nil {
-				return nil, err
-			}
+			// the command runs again by a new connection if the connection is broken
+			// before it starts, see errRetryable
+			for attempt := 0; ; attempt++ {
+				conn, err := rt.executor.GetClient(host, copts...)
+				if err != nil {
+					// the dial errors are marked by client.ErrConnect, the host is unreachable
+					return nil, err
+				}
 
-			data, err = rt.run(ctx, conn, host, shell, opts...)
-			_, e1 := rt.executor.RemoveClient(host)
-			if e1 != nil {
-				rt.Logger().Sugar().Warnf("closing connection: %v", e1)
+				data, err = rt.run(ctx, conn, host, shell, opts...)
+				rt.executor.ReleaseClient(conn)
+				if err == nil || !errors.Is(err, client.ErrConnect) {
+					return data, err
+				}
+
+				if _, e1 := rt.executor.RemoveClient(host, copts...); e1 != nil {
+					rt.Logger().Sugar().Warnf("closing connection: %v", e1)
+				}
+				if !errors.Is(err, errRetryable) || attempt > 0 || ctx.Err() != nil {
+					return data, err
+				}
+				rt.Logger().Debug("reconnect broken connection",
+					zap.String("host", host),
+					zap.Error(err))
 			}
-			return data, err
 		}
 
 		data, err := call()
@@ -221,24 +302,49 @@ func (rt *Runtime) run(ctx context.Context, conn client.IClient, host, shell str
 		return nil, errors.New("command can't be execute")
 	}
 
-	sm := rt.applyStableMap(host)
+	check := rt.opts.check || options.Check
+	if check && !cmd.Check {
+		// the command may change the host, it doesn't run in check mode
+		return json.Marshal(map[string]any{
+			"changed":     false,
+			"skipped":     true,
+			"skip_reason": NoCheckModeReason,
+		})
+	}
+
+	sm, err := rt.applyStableMap(ctx, host, conn)
+	if err != nil {
+		return nil, retryable(err)
+	}
 	if options.sync {
 		sm.Set(syncFlag, "")
 	}
 
-	if err = rt.syncRepl(ctx, conn, sm); err != nil {
-		return nil, err
+	// reports the progress of uploading toolchain and modules
+	trace := func(trace *client.IOTrace) {
+		if cb := options.Callback; cb != nil {
+			cb.RunnerOnTransfer(host, trace)
+		}
 	}
 
-	if err = rt.syncDepModules(ctx, conn, sm); err != nil {
-		return nil, err
+	// the toolchain is checked once per connection of the user
+	connKey := options.RemoteUser + "@" + host
+	if err = rt.syncRepl(ctx, connKey, conn, sm, trace); err != nil {
+		return nil, retryable(err)
 	}
 
-	if err = rt.syncModule(ctx, conn, bm, sm); err != nil {
-		return nil, err
+	if err = rt.syncDepModules(ctx, conn, sm, trace); err != nil {
+		return nil, retryable(err)
 	}
 
-	rctx := cmd.NewContext(ctx, lg, conn, sm)
+	if err = rt.syncModule(ctx, conn, bm, sm, trace); err != nil {
+		return nil, retryable(err)
+	}
+
+	// the command of module starts by the first session of conn
+	mconn := &commandClient{IClient: conn}
+	rctx := cmd.NewContext(ctx, lg, mconn, sm)
+	rctx.Redact = rt.redactFn(cmd, options)
 	eOpts := []client.ExecOption{
 		client.ExecWithRootDir(bm.Root),
 	}
@@ -248,6 +354,19 @@ func (rt *Runtime) run(ctx context.Context, conn client.IClient, host, shell str
 		extraArgs = append(extraArgs, "--"+name+"="+arg)
 	}
 	eOpts = append(eOpts, client.ExecWithArgs(extraArgs...))
+	if check {
+		eOpts = append(eOpts, client.ExecWithCheck(true))
+	}
+	if options.Diff {
+		eOpts = append(eOpts, client.ExecWithDiff(true))
+	}
+	if options.Become {
+		become, err := rt.become(host, conn, options)
+		if err != nil {
+			return nil, retryable(err)
+		}
+		eOpts = append(eOpts, client.ExecWithBecome(become))
+	}
 
 	if cmd.PreRun != nil {
 		if _, err = cmd.PreRun(rctx, eOpts...); err != nil {
@@ -259,6 +378,9 @@ func (rt *Runtime) run(ctx context.Context, conn client.IClient, host, shell str
 	}
 	out, err := cmd.Run(rctx, eOpts...)
 	if err != nil {
+		if !mconn.started.Load() {
+			err = retryable(err)
+		}
 		return nil, err
 	}
 	if cmd.PostRun != nil {
@@ -269,7 +391,31 @@ func (rt *Runtime) run(ctx context.Context, conn client.IClient, host, shell str
 	return out, nil
 }
 
-func (rt *Runtime) syncRepl(ctx context.Context, conn client.IClient, sm *module.StableMap) error {
+// retryable marks the error of broken connection by errRetryable, it is used before the
+// command of module starts only.
+func retryable(err error) error {
+	if errors.Is(err, client.ErrConnect) {
+		return errors.Mark(err, errRetryable)
+	}
+	return err
+}
+
+// commandClient records whether the command of module has started a session on the host
+type commandClient struct {
+	client.IClient
+
+	started atomic.Bool
+}
+
+func (c *commandClient) Execute(ctx context.Context, shell string, opts ...client.ExecOption) (client.ICmd, error) {
+	cmd, err := c.IClient.Execute(ctx, shell, opts...)
+	if err == nil {
+		c.started.Store(true)
+	}
+	return cmd, err
+}
+
+func (rt *Runtime) syncRepl(ctx context.Context, connKey string, conn client.IClient, sm *module.StableMap, trace client.IOTraceFn) error {
 	lg := rt.opts.logger
 	home := sm.GetDefault(vars.BeeHome, ".bee")
 	goos := sm.GetDefault(vars.BeePlatformVars, "linux")
@@ -282,18 +428,32 @@ func (rt *Runtime) syncRepl(ctx context.Context, conn client.IClient, sm *module
 	}
 
 	toSync := sm.Exists(syncFlag)
+	if !toSync && rt.replSynced(connKey, conn) {
+		return nil
+	}
 	if !toSync {
 		cmd, err := conn.Execute(ctx, repl, client.ExecWithArgs("-version"))
 		if err != nil {
 			return err
 		}
-		_, err = cmd.CombinedOutput()
+		out, err := cmd.CombinedOutput()
 		if err != nil {
 			toSync = true
+		} else {
+			// the repl which is older than the controller misses the builtin modules, e.g. "bee"
+			var tv tengo.TengoV
+			if err = json.Unmarshal(out, &tv); err != nil || tv.Revision < tengo.ReplRevision {
+				lg.Debug("outdated toolchain",
+					zap.String("remote", repl),
+					zap.Int("revision", tv.Revision),
+					zap.Int("want", tengo.ReplRevision))
+				toSync = true
+			}
 		}
 	}
 
 	if !toSync {
+		rt.markReplSynced(connKey, conn)
 		return nil
 	}
 
@@ -307,7 +467,7 @@ func (rt *Runtime) syncRepl(ctx context.Context, conn client.IClient, sm *module
 	}
 
 	start := time.Now()
-	err = conn.Put(ctx, toolchain, repl, client.PutWithMkdir(true))
+	err = conn.Put(ctx, toolchain, repl, client.PutWithMkdir(true), client.PutWithTrace(trace))
 	lg.Debug("upload toolchain",
 		zap.String("repl", "tengo"),
 		zap.String("platform", goos),
@@ -315,11 +475,28 @@ func (rt *Runtime) syncRepl(ctx context.Context, conn client.IClient, sm *module
 		zap.String("remote", repl),
 		zap.Duration("took", time.Now().Sub(start)),
 	)
+	if err == nil {
+		rt.markReplSynced(connKey, conn)
+	}
 
 	return err
 }
 
-func (rt *Runtime) syncDepModules(ctx context.Context, conn client.IClient, sm *module.StableMap) error {
+// replSynced reports whether the toolchain is checked by the connection,
+// the toolchain is checked again when the host is connected again.
+func (rt *Runtime) replSynced(connKey string, conn client.IClient) bool {
+	rt.smu.Lock()
+	defer rt.smu.Unlock()
+	return rt.synced[connKey] == conn
+}
+
+func (rt *Runtime) markReplSynced(connKey string, conn client.IClient) {
+	rt.smu.Lock()
+	defer rt.smu.Unlock()
+	rt.synced[connKey] = conn
+}
+
+func (rt *Runtime) syncDepModules(ctx context.Context, conn client.IClient, sm *module.StableMap, trace client.IOTraceFn) error {
 	root := rt.modules.RootDir()
 	modules := rt.modules.Modules()
 
@@ -336,12 +513,11 @@ func (rt *Runtime) syncDepModules(ctx context.Context, conn client.IClient, sm *
 		if goos == "windows" {
 			remoteDir = strings.ReplaceAll(remoteDir, "/", "\\")
 		}
-		rs, _ := conn.Stat(ctx, remoteDir)
-		if rs != nil {
+		if !rt.moduleOutdated(ctx, conn, item, remoteDir, goos) {
 			continue
 		}
 		start := time.Now()
-		err := conn.Put(ctx, localDir, remoteDir, client.PutWithDir(true))
+		err := conn.Put(ctx, localDir, remoteDir, client.PutWithDir(true), client.PutWithTrace(trace))
 
 		lg.Debug("put bee module",
 			zap.String("name", item.Name),
@@ -357,7 +533,28 @@ func (rt *Runtime) syncDepModules(ctx context.Context, conn client.IClient, sm *
 	return nil
 }
 
-func (rt *Runtime) syncModule(ctx context.Context, conn client.IClient, bm *module.Module, sm *module.StableMap) error {
+// moduleOutdated returns true if the module in remoteDir is missing or its version
+// differs from the local one, the module is uploaded again.
+func (rt *Runtime) moduleOutdated(ctx context.Context, conn client.IClient, bm *module.Module, remoteDir, goos string) bool {
+	rs, _ := conn.Stat(ctx, remoteDir)
+	if rs == nil {
+		return true
+	}
+
+	beePath := path.Join(remoteDir, "bee.yml")
+	if goos == "windows" {
+		beePath = strings.ReplaceAll(beePath, "/", "\\")
+	}
+	data, err := conn.ReadFile(ctx, beePath)
+	if err != nil {
+		return true
+	}
+	var om module.Module
+	_ = yaml.Unmarshal(data, &om)
+	return len(om.Version) != 0 && om.Version != bm.Version
+}
+
+func (rt *Runtime) syncModule(ctx context.Context, conn client.IClient, bm *module.Module, sm *module.StableMap, trace client.IOTraceFn) error {
 	root := rt.modules.RootDir()
 
 	lg := rt.Logger()
@@ -372,30 +569,12 @@ func (rt *Runtime) syncModule(ctx context.Context, conn client.IClient, bm *modu
 
 	toSync := sm.Exists(syncFlag)
 	if !toSync {
-		rs, _ := conn.Stat(ctx, remoteDir)
-		if rs == nil {
-			toSync = true
-		}
-
-		beePath := path.Join(remoteDir, "bee.yml")
-		if goos == "windows" {
-			beePath = strings.ReplaceAll(beePath, "/", "\\")
-		}
-		data, err := conn.ReadFile(ctx, beePath)
-		if err != nil {
-			toSync = true
-		} else {
-			var om module.Module
-			_ = yaml.Unmarshal(data, &om)
-			if len(om.Version) != 0 && om.Version != bm.Version {
-				toSync = true
-			}
-		}
+		toSync = rt.moduleOutdated(ctx, conn, bm, remoteDir, goos)
 	}
 
 	if toSync {
 		start := time.Now()
-		err := conn.Put(ctx, localDir, remoteDir, client.PutWithDir(true))
+		err := conn.Put(ctx, localDir, remoteDir, client.PutWithDir(true), client.PutWithTrace(trace))
 
 		lg.Debug("put bee module",
 			zap.String("name", bm.Name),
@@ -411,8 +590,79 @@ func (rt *Runtime) syncModule(ctx context.Context, conn client.IClient, bm *modu
 	return nil
 }
 
+// trackCallback records the callback which buffers the events or holds the resources,
+// see callback.IFlusher and callback.ICloser
+func (rt *Runtime) trackCallback(cb callback.ICallBack) {
+	flushers := callback.Flushers(cb)
+	closers := callback.Closers(cb)
+	if len(flushers) == 0 && len(closers) == 0 {
+		return
+	}
+
+	rt.fmu.Lock()
+	defer rt.fmu.Unlock()
+	for _, flusher := range flushers {
+		rt.flushers[flusher] = struct{}{}
+	}
+	for _, closer := range closers {
+		rt.closers[closer] = struct{}{}
+	}
+}
+
+// flush flushes the buffered events of callbacks
+func (rt *Runtime) flush() error {
+	rt.fmu.Lock()
+	flushers := make([]callback.IFlusher, 0, len(rt.flushers))
+	for flusher := range rt.flushers {
+		flushers = append(flushers, flusher)
+	}
+	rt.fmu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultFlushTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, flusher := range flushers {
+		if err := flusher.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeCallbacks closes the callbacks which hold the resources, e.g. the files of events
+func (rt *Runtime) closeCallbacks() error {
+	rt.fmu.Lock()
+	closers := make([]callback.ICloser, 0, len(rt.closers))
+	for closer := range rt.closers {
+		closers = append(closers, closer)
+	}
+	rt.closers = map[callback.ICloser]struct{}{}
+	rt.fmu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultFlushTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, closer := range closers {
+		if err := closer.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (rt *Runtime) Stop() error {
 	rt.pool.Release()
+	if err := rt.executor.Cleanup(); err != nil {
+		rt.Logger().Warn("close connections", zap.Error(err))
+	}
+	if err := rt.flush(); err != nil {
+		rt.Logger().Error("flush callbacks", zap.Error(err))
+	}
+	if err := rt.closeCallbacks(); err != nil {
+		rt.Logger().Error("close callbacks", zap.Error(err))
+	}
 	if err := rt.db.Flush(); err != nil {
 		return err
 	}
@@ -423,13 +673,124 @@ func (rt *Runtime) Stop() error {
 	return nil
 }
 
-func (rt *Runtime) applyStableMap(host string) *module.StableMap {
-	sm := module.NewVariables()
-	home := rt.variables.MustGetHostDefaultValue(host, vars.BeeHome, "/tmp/bee")
-	sm.Set(vars.BeeHome, home)
-	goos := rt.variables.MustGetHostDefaultValue(host, vars.BeePlatformVars, "linux")
-	sm.Set(vars.BeePlatformVars, goos)
-	arch := rt.variables.MustGetHostDefaultValue(host, vars.BeeArchVars, "amd64")
-	sm.Set(vars.BeeArchVars, arch)
-	return sm
+// becomeMethod returns the method of privilege escalation on host,
+// it defaults to runas for the windows hosts and sudo for the others.
+func (rt *Runtime) becomeMethod(host string) string {
+	method := rt.variables.MustGetHostDefaultValue(host, vars.BeeBecomeMethodVars, "")
+	if method != "" {
+		return method
+	}
+	if rt.executor.ConnectKind(host) == client.WinRMClient {
+		return client.BecomeRunas
+	}
+	return client.BecomeSudo
+}
+
+func (rt *Runtime) becomeUser(host string, options *RunOptions) string {
+	if options.BecomeUser != "" {
+		return options.BecomeUser
+	}
+	user := rt.variables.MustGetHostDefaultValue(host, vars.BeeBecomeUserVars, "")
+	if user == "" && rt.becomeMethod(host) != client.BecomeRunas {
+		user = "root"
+	}
+	return user
+}
+
+// become builds the privilege escalation of the modules on host, the password is looked up
+// from PasswordManager in namespace "become", then the variable bee_become_passwd. sudo asks
+// the password of connection user, so it falls back to the password of connection.
+func (rt *Runtime) become(host string, conn client.IClient, options *RunOptions) (*client.Become, error) {
+	method := rt.becomeMethod(host)
+	become := &client.Become{
+		Method: method,
+		User:   rt.becomeUser(host, options),
+	}
+	if method == client.BecomeRunas && become.User == "" {
+		return nil, fmt.Errorf("missing become user of host '%s'", host)
+	}
+
+	passwd, _ := rt.passwords.GetRawPassword(host, secret.WithNamespace("become"))
+	if passwd == "" {
+		passwd = rt.variables.MustGetHostDefaultValue(host, vars.BeeBecomePasswdVars, "")
+	}
+	if passwd == "" && method == client.BecomeSudo && conn.Name() == client.SSHClient {
+		if options.RemoteUser != "" {
+			passwd, _ = rt.passwords.GetRawPassword(options.RemoteUser+"@"+host, secret.WithNamespace("ssh"))
+		} else {
+			passwd, _ = rt.passwords.GetRawPassword(host, secret.WithNamespace("ssh"))
+			if passwd == "" {
+				passwd = rt.variables.MustGetHostDefaultValue(host, vars.BeeSSHPasswdVars, "")
+			}
+		}
+	}
+	become.Password = passwd
+
+	return become, nil
+}
+
+// redactFn returns the function which masks the secrets in the logs of command, the values
+// of secret params are added to the secret filters of run, so that they are masked in the
+// outputs too.
+func (rt *Runtime) redactFn(cmd *module.Command, options *RunOptions) func(text string) string {
+	if options.NoLog {
+		return func(text string) string { return module.RedactedValue }
+	}
+
+	sfs := filter.SecretFilters(options.Filter)
+	if len(sfs) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0)
+	for _, param := range cmd.Params {
+		if !param.Secret {
+			continue
+		}
+		if flag := cmd.Flags().Lookup(param.Name); flag != nil {
+			values = append(values, flag.Value.String())
+		}
+		if value, ok := options.ExtraArgs[param.Name]; ok {
+			values = append(values, value)
+		}
+	}
+	for _, sf := range sfs {
+		sf.AddSecrets(values...)
+	}
+
+	return func(text string) string {
+		for _, sf := range sfs {
+			text = sf.Redact(text)
+		}
+		return text
+	}
+}
+
+// hostSecrets returns the passwords of host in PasswordManager
+func (rt *Runtime) hostSecrets(host string, options *RunOptions) []string {
+	keys := []string{host}
+	if options.RemoteUser != "" {
+		keys = append(keys, options.RemoteUser+"@"+host)
+	}
+
+	values := make([]string, 0)
+	for _, key := range keys {
+		if passwd, _ := rt.passwords.GetRawPassword(key, secret.WithNamespace("ssh")); passwd != "" {
+			values = append(values, passwd)
+		}
+	}
+	if passwd, _ := rt.passwords.GetRawPassword(host, secret.WithNamespace("become")); passwd != "" {
+		values = append(values, passwd)
+	}
+	return values
+}
+
+// executionUser returns the effective user who runs the modules on host
+func (rt *Runtime) executionUser(host string, options *RunOptions) string {
+	if options.Become {
+		if user := rt.becomeUser(host, options); user != "" {
+			return user
+		}
+	}
+	return rt.executor.User(host, bexecutor.WithUser(options.RemoteUser))
 }
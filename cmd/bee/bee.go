@@ -18,15 +18,54 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
+	jsoniter "github.com/json-iterator/go"
 	"github.com/spf13/cobra"
+
+	"github.com/olive-io/bee"
 )
 
+// json sorts the keys of maps like encoding/json, the outputs of commands are stable
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
 func main() {
+	options := newGlobalOptions()
+
 	root := &cobra.Command{
-		Use: "bee",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return nil
-		},
+		Use:           "bee",
+		Short:         "bee runs modules and processes on remote hosts",
+		Version:       bee.Version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
-	root.Execute()
+	options.addFlags(root.PersistentFlags())
+
+	root.AddCommand(newRunCommand(options))
+	root.AddCommand(newPlayCommand(options))
+	root.AddCommand(newHistoryCommand(options))
+
+	if err := root.Execute(); err != nil {
+		code := 1
+		var ee *exitError
+		if errors.As(err, &ee) {
+			code = ee.code
+		}
+		if ee == nil || ee.msg != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
+	}
+}
+
+// exitError carries the exit code of the bee command
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
 }
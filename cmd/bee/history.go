@@ -0,0 +1,273 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/olive-io/bee"
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/history"
+	"github.com/olive-io/bee/stats"
+)
+
+type historyOptions struct {
+	*globalOptions
+
+	host    string
+	process string
+	since   string
+	until   string
+	limit   int
+	json    bool
+}
+
+func newHistoryCommand(global *globalOptions) *cobra.Command {
+	options := &historyOptions{globalOptions: global}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the runs of play and run commands",
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the runs from newest to oldest",
+		Example: `  bee history list --host web1 --since 2024-05-07 --until 2024-05-08
+  bee history list --process site --since 24h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listHistory(cmd.OutOrStdout(), options)
+		},
+	}
+	flags := list.Flags()
+	flags.StringVar(&options.host, "host", "", "only the runs on the host")
+	flags.StringVar(&options.process, "process", "", "only the runs of the process, by name or id")
+	flags.StringVar(&options.since, "since", "", "only the runs started after the time, a date, RFC3339 time or duration ago (e.g. 24h)")
+	flags.StringVar(&options.until, "until", "", "only the runs started before the time, a date, RFC3339 time or duration ago")
+	flags.IntVarP(&options.limit, "limit", "n", 20, "the max number of runs, 0 means no limit")
+
+	show := &cobra.Command{
+		Use:     "show <id>",
+		Short:   "Show the results of the run",
+		Example: `  bee history show 17ccd1b0e8a5f2c01a2b3c`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showHistory(cmd.OutOrStdout(), options, args[0])
+		},
+	}
+	show.Flags().BoolVar(&options.json, "json", false, "print the run as json")
+
+	checkpoints := &cobra.Command{
+		Use:     "checkpoints",
+		Short:   "List the failed and unfinished plays which can be resumed",
+		Example: `  bee history checkpoints`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listCheckpoints(cmd.OutOrStdout(), options)
+		},
+	}
+
+	cmd.AddCommand(list, show, checkpoints)
+	return cmd
+}
+
+func (o *historyOptions) openHistory() (*history.Store, func() error, error) {
+	lg, err := o.logger()
+	if err != nil {
+		return nil, nil, err
+	}
+	return bee.OpenHistory(lg, o.dir)
+}
+
+func listHistory(out io.Writer, options *historyOptions) error {
+	opts := []history.ListOption{
+		history.WithHost(options.host),
+		history.WithProcess(options.process),
+		history.WithLimit(options.limit),
+	}
+	if options.since != "" {
+		since, err := parseTime(options.since, time.Now())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, history.WithSince(since))
+	}
+	if options.until != "" {
+		until, err := parseTime(options.until, time.Now())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, history.WithUntil(until))
+	}
+
+	store, closer, err := options.openHistory()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	runs, err := store.List(opts...)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tKIND\tNAME\tHOSTS\tSTART\tDURATION\tSTATUS")
+	for _, run := range runs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.Id, run.Kind, run.Name(), strings.Join(run.Hosts, ","),
+			run.StartAt.Local().Format(time.DateTime), runDuration(run), runStatus(run))
+	}
+	return tw.Flush()
+}
+
+// listCheckpoints lists the checkpoints of plays which failed or didn't end, from newest to oldest
+func listCheckpoints(out io.Writer, options *historyOptions) error {
+	store, closer, err := options.openHistory()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	cps, err := store.ListCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tNAME\tRESUMED\tCOMPLETED\tUPDATE\tSTATUS")
+	for _, cp := range cps {
+		// the run is missing if the play was interrupted before it was recorded
+		status := "UNFINISHED"
+		if run, err := store.Get(cp.RunId); err == nil {
+			status = runStatus(run)
+		}
+		if status == "OK" {
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			cp.RunId, cp.Name, cp.Resumed, len(cp.Completed),
+			cp.UpdateAt.Local().Format(time.DateTime), status)
+	}
+	return tw.Flush()
+}
+
+// runStatus returns the status of run, UNFINISHED if it is running or was interrupted
+func runStatus(run *history.Run) string {
+	switch {
+	case run.Unfinished():
+		return "UNFINISHED"
+	case run.Failed():
+		return "FAILED"
+	default:
+		return "OK"
+	}
+}
+
+// runDuration returns the duration of run, "-" if it didn't end
+func runDuration(run *history.Run) string {
+	if run.Unfinished() {
+		return "-"
+	}
+	return run.EndAt.Sub(run.StartAt).Round(time.Millisecond).String()
+}
+
+func showHistory(out io.Writer, options *historyOptions, id string) error {
+	store, closer, err := options.openHistory()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	run, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("run '%s': %w", id, err)
+	}
+
+	if options.json {
+		data, _ := json.MarshalIndent(run, "", "  ")
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+
+	_, _ = fmt.Fprintf(out, "id:       %s\n", run.Id)
+	_, _ = fmt.Fprintf(out, "kind:     %s\n", run.Kind)
+	_, _ = fmt.Fprintf(out, "name:     %s\n", run.Name())
+	_, _ = fmt.Fprintf(out, "hosts:    %s\n", strings.Join(run.Hosts, ","))
+	_, _ = fmt.Fprintf(out, "start:    %s\n", run.StartAt.Local().Format(time.RFC3339))
+	_, _ = fmt.Fprintf(out, "duration: %s\n", runDuration(run))
+	_, _ = fmt.Fprintf(out, "status:   %s\n", runStatus(run))
+	if run.Error != "" {
+		_, _ = fmt.Fprintf(out, "error:    %s\n", run.Error)
+	}
+
+	// replays the results by printer, likes the output of play
+	as := summaryStats(run.Stats)
+	p := newPrinter(out, true)
+	for _, result := range run.Results {
+		switch {
+		case result.ErrMsg != "" && as.Get(bexecutor.Dark, result.Host) > 0:
+			p.RunnerOnUnreachable(result)
+		case result.ErrMsg != "":
+			p.RunnerOkFailed(result)
+		case stats.IsSkipped(result.Stdout):
+			p.RunnerOnSkipped(result)
+		default:
+			p.RunnerOnOk(result)
+		}
+	}
+	p.Recap(as)
+	return nil
+}
+
+// summaryStats rebuilds AggregateStats from the summary of hosts in history.Run
+func summaryStats(summary map[string]map[string]int64) *bexecutor.AggregateStats {
+	as := bexecutor.NewStats()
+	zones := []bexecutor.Zone{
+		bexecutor.Ok, bexecutor.Dark, bexecutor.Changed, bexecutor.Skipped,
+		bexecutor.Rescued, bexecutor.Ignored, bexecutor.Failures,
+	}
+	for host, counts := range summary {
+		for _, zone := range zones {
+			for i := int64(0); i < counts[zone.String()]; i++ {
+				as.Increment(zone, host)
+			}
+		}
+	}
+	return as
+}
+
+// parseTime parses the date (2006-01-02), RFC3339 time or the duration before now (24h)
+func parseTime(text string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(text); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, text, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', expect a date, RFC3339 time or duration", text)
+}
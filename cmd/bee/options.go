@@ -0,0 +1,136 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee"
+	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/plugins"
+	"github.com/olive-io/bee/vars"
+)
+
+type globalOptions struct {
+	dir       string
+	inventory string
+	config    string
+	forks     int
+	verbose   bool
+}
+
+func newGlobalOptions() *globalOptions {
+	home, _ := os.UserHomeDir()
+	options := &globalOptions{
+		dir:   filepath.Join(home, ".bee"),
+		forks: bee.DefaultParallel,
+	}
+	return options
+}
+
+func (o *globalOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.dir, "dir", o.dir, "the root directory of bee, contains modules and repl toolchains")
+	flags.StringVarP(&o.inventory, "inventory", "i", o.inventory, "the path of inventory file (default <dir>/inventory/hosts)")
+	flags.StringVarP(&o.config, "config", "c", o.config, "the path of config file which enables the plugins (default <dir>/config.yml)")
+	flags.IntVarP(&o.forks, "forks", "f", o.forks, "the number of hosts to run at the same time")
+	flags.BoolVarP(&o.verbose, "verbose", "v", o.verbose, "print debug logs")
+}
+
+func (o *globalOptions) logger() (*zap.Logger, error) {
+	if !o.verbose {
+		return zap.NewNop(), nil
+	}
+	return zap.NewDevelopment()
+}
+
+// newRuntime loads the inventory and builds bee.Runtime by globalOptions
+func (o *globalOptions) newRuntime(opts ...bee.Option) (*bee.Runtime, *inv.Manager, error) {
+	lg, err := o.logger()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inventory := o.inventory
+	if inventory == "" {
+		inventory = filepath.Join(o.dir, "inventory", "hosts")
+	}
+
+	dataloader := parser.NewDataLoader()
+	if err = dataloader.ParseFile(inventory); err != nil {
+		return nil, nil, errors.Wrapf(err, "parse inventory")
+	}
+	// loads group_vars and host_vars beside the inventory file
+	if err = dataloader.AddVars(filepath.Dir(inventory)); err != nil {
+		return nil, nil, errors.Wrapf(err, "load inventory variables")
+	}
+
+	manager, err := inv.NewInventoryManager(dataloader)
+	if err != nil {
+		return nil, nil, err
+	}
+	variables := vars.NewVariablesManager(dataloader, manager)
+
+	options := []bee.Option{
+		bee.SetDir(o.dir),
+		bee.SetLogger(lg),
+	}
+	if o.forks > 0 {
+		options = append(options, bee.SetParallel(o.forks))
+	}
+	options = append(options, opts...)
+
+	rt, err := bee.NewRuntime(manager, variables, dataloader, options...)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "create bee runtime")
+	}
+	return rt, manager, nil
+}
+
+// pluginOptions builds the plugins enabled in config file, the default config file is optional
+func (o *globalOptions) pluginOptions() ([]bee.RunOption, error) {
+	name := o.config
+	if name == "" {
+		name = filepath.Join(o.dir, "config.yml")
+		if _, err := os.Stat(name); err != nil {
+			return nil, nil
+		}
+	}
+
+	cfg, err := plugins.LoadConfig(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load config")
+	}
+	callbacks, filters, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	return []bee.RunOption{bee.WithRunCallback(callbacks...), bee.WithRunFilter(filters...)}, nil
+}
+
+func (o *globalOptions) parallel() int {
+	if o.forks > 0 {
+		return o.forks
+	}
+	return bee.DefaultParallel
+}
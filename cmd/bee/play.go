@@ -0,0 +1,283 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/olive-io/bee"
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/history"
+	"github.com/olive-io/bee/process"
+)
+
+type playOptions struct {
+	*globalOptions
+
+	limit     string
+	extraVars []string
+	check     bool
+	diff      bool
+	sync      bool
+	resume    string
+}
+
+func newPlayCommand(global *globalOptions) *cobra.Command {
+	options := &playOptions{globalOptions: global}
+	cmd := &cobra.Command{
+		Use:   "play <file>",
+		Short: "Run the processes defined in the yaml file",
+		Example: `  bee play -i hosts site.yml
+  bee play -i hosts site.yml --limit web -e version=1.0.1 --check --diff
+  bee play -i hosts --resume <id>`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if options.resume != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.resume != "" {
+				return runResume(cmd.Context(), cmd.OutOrStdout(), options)
+			}
+			return runPlay(cmd.Context(), cmd.OutOrStdout(), options, args[0])
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&options.limit, "limit", "l", "", "further limit the hosts to the pattern, separated by ','")
+	flags.StringArrayVarP(&options.extraVars, "extra-vars", "e", nil, "set additional variables as key=value, a json/yaml object or @file")
+	flags.BoolVarP(&options.check, "check", "C", false, "don't make any changes, try to predict some of the changes that may occur")
+	flags.BoolVarP(&options.diff, "diff", "D", false, "show the differences of the files changed by modules")
+	flags.BoolVar(&options.sync, "sync", false, "upload the toolchain and modules even if they already exist on the remote host")
+	flags.StringVar(&options.resume, "resume", "", "resume the interrupted play by the run id, only the failed and pending tasks run again")
+
+	return cmd
+}
+
+func runPlay(ctx context.Context, out io.Writer, options *playOptions, name string) error {
+	processes, err := loadProcesses(name)
+	if err != nil {
+		return err
+	}
+
+	extraVars, err := parseExtraVars(options.extraVars)
+	if err != nil {
+		return err
+	}
+
+	rt, inventory, err := options.newRuntime(bee.SetCheck(options.check))
+	if err != nil {
+		return err
+	}
+	defer rt.Stop()
+
+	pluginOpts, err := options.pluginOptions()
+	if err != nil {
+		return err
+	}
+
+	printer := newPrinter(out, options.verbose)
+	printer.diff = options.diff
+	runOpts := []bee.RunOption{
+		bee.WithRunSync(options.sync),
+		bee.WithRunDiff(options.diff),
+		bee.WithRunCallback(printer),
+		bee.WithRunExtraVars(extraVars),
+	}
+	runOpts = append(runOpts, pluginOpts...)
+
+	if options.limit != "" {
+		hosts, err := inventory.ResolveHosts(strings.Split(options.limit, ",")...)
+		if err != nil {
+			return err
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("no hosts matched the limit '%s'", options.limit)
+		}
+		runOpts = append(runOpts, bee.WithRunLimit(hosts...))
+	}
+
+	// sums the stats of all processes for recap
+	as := bexecutor.NewStats()
+	failed := false
+	// the failed plays could be resumed by the run id
+	resumable := make([]*bee.RunReport, 0)
+	for _, pr := range processes {
+		// prints the id first, the interrupted play is resumed by it
+		runId := history.NewRunId(time.Now())
+		fmt.Fprintf(out, "run %s of process '%s'\n", runId, pr.Name)
+
+		var report *bee.RunReport
+		report, err = rt.Play(ctx, pr, append(runOpts, bee.WithRunId(runId))...)
+		as.Merge(report.Stats)
+		failed = failed || report.Failed()
+		if err != nil || report.Failed() {
+			resumable = append(resumable, report)
+		}
+		if err != nil {
+			break
+		}
+	}
+	printer.Recap(as)
+	for _, report := range resumable {
+		fmt.Fprintf(out, "resume the process '%s' by: bee play --resume %s\n", report.Process, report.RunId)
+	}
+
+	if err != nil {
+		return &exitError{code: 2, msg: err.Error()}
+	}
+	if failed {
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+// runResume resumes the play of run, the process, limit, extra vars, check and diff are restored
+// from the run. The extra vars override the restored ones, e.g. the masked secrets.
+func runResume(ctx context.Context, out io.Writer, options *playOptions) error {
+	if options.limit != "" || options.check {
+		return fmt.Errorf("--limit and --check are restored from the run, they can't be used with --resume")
+	}
+
+	extraVars, err := parseExtraVars(options.extraVars)
+	if err != nil {
+		return err
+	}
+
+	rt, _, err := options.newRuntime()
+	if err != nil {
+		return err
+	}
+	defer rt.Stop()
+
+	pluginOpts, err := options.pluginOptions()
+	if err != nil {
+		return err
+	}
+
+	printer := newPrinter(out, options.verbose)
+	printer.diff = options.diff
+	runId := history.NewRunId(time.Now())
+	runOpts := []bee.RunOption{
+		bee.WithRunId(runId),
+		bee.WithRunSync(options.sync),
+		bee.WithRunCallback(printer),
+		bee.WithRunExtraVars(extraVars),
+	}
+	if options.diff {
+		runOpts = append(runOpts, bee.WithRunDiff(true))
+	}
+	runOpts = append(runOpts, pluginOpts...)
+
+	fmt.Fprintf(out, "run %s resumes run %s\n", runId, options.resume)
+	report, err := rt.Resume(ctx, options.resume, runOpts...)
+	if report == nil {
+		return err
+	}
+	printer.Recap(report.Stats)
+
+	if err != nil {
+		return &exitError{code: 2, msg: err.Error()}
+	}
+	if report.Failed() {
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+// loadProcesses reads process.Process from yaml file, the file contains a single process or a list of processes
+func loadProcesses(name string) ([]*process.Process, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(data, &node); err != nil {
+		return nil, errors.Wrapf(err, "parse '%s'", name)
+	}
+	if len(node.Content) == 0 {
+		return nil, fmt.Errorf("'%s' is empty", name)
+	}
+
+	processes := make([]*process.Process, 0)
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err = node.Decode(&processes)
+	} else {
+		pr := &process.Process{}
+		err = node.Decode(pr)
+		processes = append(processes, pr)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse '%s'", name)
+	}
+
+	return processes, nil
+}
+
+// parseExtraVars parses extra variables, each of them could be key=value, a json/yaml object or @file
+func parseExtraVars(values []string) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		var data []byte
+		switch {
+		case strings.HasPrefix(value, "@"):
+			content, err := os.ReadFile(strings.TrimPrefix(value, "@"))
+			if err != nil {
+				return nil, errors.Wrapf(err, "read extra vars")
+			}
+			data = content
+		case strings.HasPrefix(value, "{"):
+			data = []byte(value)
+		default:
+			for _, item := range strings.Fields(value) {
+				key, val, ok := strings.Cut(item, "=")
+				if !ok || key == "" {
+					return nil, fmt.Errorf("invalid extra vars '%s', expect key=value", item)
+				}
+				vars[key] = val
+			}
+			continue
+		}
+
+		kv := map[string]any{}
+		if err := yaml.Unmarshal(data, &kv); err != nil {
+			return nil, errors.Wrapf(err, "parse extra vars")
+		}
+		for key, val := range kv {
+			vars[key] = val
+		}
+	}
+
+	return vars, nil
+}
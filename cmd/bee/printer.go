@@ -0,0 +1,201 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// printer implements callback.ICallBack, writes the events to terminal
+type printer struct {
+	callback.BaseCallBack
+
+	mu      sync.Mutex
+	out     io.Writer
+	verbose bool
+	diff    bool
+	task    string
+}
+
+func newPrinter(out io.Writer, verbose bool) *printer {
+	p := &printer{
+		out:     out,
+		verbose: verbose,
+	}
+	return p
+}
+
+func (p *printer) PlayOnStart(pr *process.Process) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := pr.Name
+	if name == "" {
+		name = strings.Join(pr.Hosts, ",")
+	}
+	p.task = ""
+	p.banner("PLAY", name)
+}
+
+func (p *printer) TaskOnStart(task process.INamedTask, hosts []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.task = task.GetId()
+	name := task.GetName()
+	if name == "" {
+		name = task.GetId()
+	}
+	p.banner("TASK", name)
+}
+
+func (p *printer) HandlerOnStart(handler *process.Handler, hosts []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlerOnStart("RUNNING HANDLER", handler)
+}
+
+func (p *printer) HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlerOnStart("CATCH", handler)
+}
+
+func (p *printer) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlerOnStart("FINISH", handler)
+}
+
+func (p *printer) RunnerOnRetry(result *stats.TaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.taskOnStart(result)
+	_, _ = fmt.Fprintf(p.out, "retrying: [%s] (attempt %d) => %s\n", result.Host, result.Attempts, result.ErrMsg)
+}
+
+func (p *printer) RunnerOnUnreachable(result *stats.TaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.taskOnStart(result)
+	_, _ = fmt.Fprintf(p.out, "unreachable: [%s] => %s\n", result.Host, result.ErrMsg)
+}
+
+func (p *printer) RunnerOnOk(result *stats.TaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.taskOnStart(result)
+	status := "ok"
+	if result.Changed {
+		status = "changed"
+	}
+	if !p.verbose {
+		_, _ = fmt.Fprintf(p.out, "%s: [%s]\n", status, result.Host)
+	} else {
+		data, _ := json.MarshalIndent(result.Stdout, "", "  ")
+		_, _ = fmt.Fprintf(p.out, "%s: [%s] => %s\n", status, result.Host, data)
+	}
+	if p.diff {
+		_ = stats.RenderDiff(p.out, result)
+	}
+}
+
+func (p *printer) RunnerOkFailed(result *stats.TaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.taskOnStart(result)
+	_, _ = fmt.Fprintf(p.out, "failed: [%s] => %s\n", result.Host, result.ErrMsg)
+}
+
+func (p *printer) RunnerOnSkipped(result *stats.TaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.taskOnStart(result)
+	_, _ = fmt.Fprintf(p.out, "skipping: [%s]\n", result.Host)
+}
+
+// Recap writes the summary of hosts, likes the recap of ansible
+func (p *printer) Recap(as *bexecutor.AggregateStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.banner("PLAY RECAP", "")
+	for _, host := range as.Hosts() {
+		_, _ = fmt.Fprintf(p.out, "%-26s : ok=%-4d changed=%-4d unreachable=%-4d failed=%-4d skipped=%-4d rescued=%-4d ignored=%-4d\n",
+			host,
+			as.Get(bexecutor.Ok, host),
+			as.Get(bexecutor.Changed, host),
+			as.Get(bexecutor.Dark, host),
+			as.Get(bexecutor.Failures, host),
+			as.Get(bexecutor.Skipped, host),
+			as.Get(bexecutor.Rescued, host),
+			as.Get(bexecutor.Ignored, host))
+	}
+}
+
+// taskOnStart writes the task banner when the result belongs to a new task
+func (p *printer) taskOnStart(result *stats.TaskResult) {
+	if result.TaskId == p.task {
+		return
+	}
+	p.task = result.TaskId
+
+	name := result.Task
+	if name == "" {
+		name = result.TaskId
+	}
+	p.banner("TASK", name)
+}
+
+// handlerOnStart writes the banner of handler, the results of handler belong to it
+func (p *printer) handlerOnStart(kind string, handler *process.Handler) {
+	p.task = handler.Id
+	name := handler.Name
+	if name == "" {
+		name = handler.Action
+	}
+	p.banner(kind, name)
+}
+
+func (p *printer) banner(kind, name string) {
+	text := kind
+	if name != "" {
+		text = fmt.Sprintf("%s [%s]", kind, name)
+	}
+	fill := 80 - len(text) - 1
+	if fill < 3 {
+		fill = 3
+	}
+	_, _ = fmt.Fprintf(p.out, "\n%s %s\n", text, strings.Repeat("*", fill))
+}
@@ -0,0 +1,153 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/olive-io/bee"
+	"github.com/olive-io/bee/stats"
+)
+
+type runOptions struct {
+	*globalOptions
+
+	sync bool
+	diff bool
+}
+
+func newRunCommand(global *globalOptions) *cobra.Command {
+	options := &runOptions{globalOptions: global}
+	cmd := &cobra.Command{
+		Use:   "run <pattern> <module> [args...]",
+		Short: "Run a module on all hosts matching the pattern",
+		Example: `  bee run -i hosts all ping
+  bee run -i hosts web,db ping data=hello
+  bee run -i hosts web copy src=/tmp/app.conf dst=/etc/app.conf --diff`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runModule(cmd.Context(), cmd.OutOrStdout(), options, args[0], args[1:])
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&options.diff, "diff", "D", false, "show the differences of the files changed by the module")
+	flags.BoolVar(&options.sync, "sync", false, "upload the toolchain and modules even if they already exist on the remote host")
+
+	return cmd
+}
+
+func runModule(ctx context.Context, out io.Writer, options *runOptions, pattern string, args []string) error {
+	rt, inventory, err := options.newRuntime()
+	if err != nil {
+		return err
+	}
+	defer rt.Stop()
+
+	hosts, err := inventory.ResolveHosts(strings.Split(pattern, ",")...)
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts matched the pattern '%s'", pattern)
+	}
+
+	shell := strings.Join(args, " ")
+	pluginOpts, err := options.pluginOptions()
+	if err != nil {
+		return err
+	}
+	runOpts := []bee.RunOption{bee.WithRunSync(options.sync), bee.WithRunDiff(options.diff)}
+	runOpts = append(runOpts, pluginOpts...)
+
+	results := make([]*stats.TaskResult, len(hosts))
+	limit := make(chan struct{}, options.parallel())
+	var wg sync.WaitGroup
+	for i := range hosts {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			results[i] = executeOn(ctx, rt, hosts[i], shell, runOpts...)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := printResults(out, results)
+	if options.diff {
+		for _, result := range results {
+			_ = stats.RenderDiff(out, result)
+		}
+	}
+	if failed > 0 {
+		return &exitError{code: 2}
+	}
+	return nil
+}
+
+func executeOn(ctx context.Context, rt *bee.Runtime, host, shell string, opts ...bee.RunOption) *stats.TaskResult {
+	result := &stats.TaskResult{Host: host}
+	data, err := rt.Execute(ctx, host, shell, opts...)
+	if err != nil {
+		result.ErrMsg = err.Error()
+		return result
+	}
+
+	stdout := map[string]any{}
+	if err = json.Unmarshal(data, &stdout); err != nil {
+		stdout = map[string]any{"stdout": string(data)}
+	}
+	result.Stdout = stdout
+	return result
+}
+
+// printResults writes the result table and the recap, returns the number of failed hosts
+func printResults(out io.Writer, results []*stats.TaskResult) int {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "HOST\tSTATUS\tRESULT")
+
+	failed := 0
+	for _, result := range results {
+		status := "OK"
+		message := ""
+		if result.ErrMsg != "" {
+			failed += 1
+			status = "FAILED"
+			message = result.ErrMsg
+		} else {
+			data, _ := json.Marshal(result.Stdout)
+			message = string(data)
+		}
+		message = strings.ReplaceAll(strings.TrimSpace(message), "\n", " ")
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Host, status, message)
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(out, "\nRECAP: hosts=%d ok=%d failed=%d\n", len(results), len(results)-failed, failed)
+	return failed
+}
@@ -348,6 +348,7 @@ func printVersion() {
 		GoVersion: runtime.Version(),
 		Goos:      runtime.GOOS,
 		Platform:  runtime.GOARCH,
+		Revision:  bt.ReplRevision,
 	}
 	data, _ := json.Marshal(tv)
 	fmt.Fprintf(os.Stdout, string(data))
@@ -34,6 +34,17 @@ const (
 	SSHClient   = "ssh"
 	WinRMClient = "winrm"
 	GRPCClient  = "grpc"
+	// LocalClient runs the commands on the controller itself
+	LocalClient = "local"
+	// SmartClient probes the host for one of ssh, winrm and grpc connections
+	SmartClient = "smart"
+)
+
+const (
+	// EnvCheckMode is the environment variable of remote process, it is "true" in check mode
+	EnvCheckMode = "BEE_CHECK_MODE"
+	// EnvDiffMode is the environment variable of remote process, it is "true" in diff mode
+	EnvDiffMode = "BEE_DIFF_MODE"
 )
 
 type IClient interface {
@@ -51,6 +62,13 @@ type IClient interface {
 	Close() error
 }
 
+// IHealthChecker is implemented by the clients which keep a connection to the
+// remote host, the connection is checked before the client is reused.
+type IHealthChecker interface {
+	// Ping returns error if the connection is broken
+	Ping(ctx context.Context) error
+}
+
 type Stat struct {
 	Name    string
 	IsDir   bool
@@ -160,6 +178,22 @@ type IOTrace struct {
 
 type IOTraceFn func(*IOTrace)
 
+const (
+	BecomeSudo  = "sudo"
+	BecomeSu    = "su"
+	BecomeRunas = "runas"
+)
+
+// Become describes the privilege escalation of command
+type Become struct {
+	// Method is the way to escalate, sudo and su for unix hosts, runas for windows hosts
+	Method string
+	// User is the user to become
+	User string
+	// Password answers the password prompt of escalation
+	Password string
+}
+
 type ExecOptions struct {
 	Context context.Context
 
@@ -167,6 +201,11 @@ type ExecOptions struct {
 	Args         []string
 	Environments map[string]string
 	Timeout      time.Duration
+	Become       *Become
+	// Check runs the command in check mode, the command reports the changes without making them
+	Check bool
+	// Diff asks the command to report the unified diff of changed content
+	Diff bool
 }
 
 func NewExecOptions() *ExecOptions {
@@ -201,6 +240,27 @@ func ExecWithEnv(key, value string) ExecOption {
 	}
 }
 
+// ExecWithBecome executes the command as another user
+func ExecWithBecome(become *Become) ExecOption {
+	return func(options *ExecOptions) {
+		options.Become = become
+	}
+}
+
+// ExecWithCheck executes the command in check mode
+func ExecWithCheck(check bool) ExecOption {
+	return func(options *ExecOptions) {
+		options.Check = check
+	}
+}
+
+// ExecWithDiff executes the command in diff mode
+func ExecWithDiff(diff bool) ExecOption {
+	return func(options *ExecOptions) {
+		options.Diff = diff
+	}
+}
+
 func ExecWithTimeout(timeout time.Duration) ExecOption {
 	return func(options *ExecOptions) {
 		options.Timeout = timeout
@@ -28,4 +28,5 @@ var (
 	ErrRequest       = errors.New("request exception")
 	ErrNotExists     = errors.New("file does not exist")
 	ErrAlreadyExists = errors.New("file already exists")
+	ErrNotSupported  = errors.New("operation not supported")
 )
@@ -442,6 +442,9 @@ func (c *Client) Execute(ctx context.Context, shell string, opts ...client.ExecO
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.Become != nil {
+		return nil, errors.Wrapf(client.ErrNotSupported, "become by grpc client")
+	}
 
 	cc, release, err := c.newConn(ctx)
 	if err != nil {
@@ -0,0 +1,46 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+// Package shell builds the command lines of posix shell which the ssh and local clients run
+package shell
+
+import (
+	"sort"
+	"strings"
+)
+
+// Export exports the environment variables before the shell
+func Export(shell string, envs map[string]string) string {
+	keys := make([]string, 0, len(envs))
+	for key := range envs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	exports := make([]string, 0, len(keys))
+	for _, key := range keys {
+		exports = append(exports, "export "+key+"="+Quote(envs[key])+";")
+	}
+	if len(exports) == 0 {
+		return shell
+	}
+	return strings.Join(exports, " ") + " " + shell
+}
+
+// Quote quotes s as a single argument of posix shell
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
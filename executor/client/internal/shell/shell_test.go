@@ -0,0 +1,36 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	shell := Export("id", map[string]string{"BEE_CHECK_MODE": "true", "A": "it's"})
+	assert.Equal(t, `export A='it'\''s'; export BEE_CHECK_MODE='true'; id`, shell)
+
+	assert.Equal(t, "id", Export("id", nil))
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `'echo ok'`, Quote("echo ok"))
+	assert.Equal(t, `'it'\''s'`, Quote("it's"))
+}
@@ -0,0 +1,173 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/olive-io/bee/executor/client"
+	bshell "github.com/olive-io/bee/executor/client/internal/shell"
+)
+
+const (
+	DefaultBecomeUser = "root"
+)
+
+// Cmd is the command runs by the shell of controller, likes the command runs by ssh
+type Cmd struct {
+	ctx context.Context
+	cmd *exec.Cmd
+
+	shell  string
+	root   string
+	name   string
+	args   []string
+	envs   map[string]string
+	become *client.Become
+}
+
+// text returns the command line, changes the working directory to root first
+func (c *Cmd) text() string {
+	args := make([]string, 0)
+	if c.root != "" {
+		if runtime.GOOS == "windows" {
+			args = append(args, "cd /d "+c.root+" &&")
+		} else {
+			args = append(args, "cd "+c.root+";")
+		}
+	}
+	args = append(args, c.name)
+	args = append(args, c.args...)
+	return strings.Join(args, " ")
+}
+
+// build builds the exec.Cmd, it is built once before the pipes or Start
+func (c *Cmd) build() (*exec.Cmd, error) {
+	if c.cmd != nil {
+		return c.cmd, nil
+	}
+
+	var cmd *exec.Cmd
+	if become := c.become; become != nil {
+		if runtime.GOOS == "windows" || (become.Method != "" && become.Method != client.BecomeSudo) {
+			return nil, errors.Wrapf(client.ErrNotSupported, "become method '%s' of local connection", become.Method)
+		}
+		user := become.User
+		if user == "" {
+			user = DefaultBecomeUser
+		}
+		// sudo resets the environment variables, they are exported in shell
+		shell := bshell.Export(c.text(), c.envs)
+		cmd = exec.CommandContext(c.ctx, "sudo", "-S", "-p", "", "-u", user, "--", c.shell, "-c", shell)
+		if become.Password != "" {
+			cmd.Stdin = strings.NewReader(become.Password + "\n")
+		}
+	} else {
+		flag := "-c"
+		if runtime.GOOS == "windows" {
+			flag = "/C"
+		}
+		cmd = exec.CommandContext(c.ctx, c.shell, flag, c.text())
+		cmd.Env = os.Environ()
+		for key, value := range c.envs {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	c.cmd = cmd
+	return cmd, nil
+}
+
+func (c *Cmd) StdinPipe() (io.WriteCloser, error) {
+	cmd, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	return cmd.StdinPipe()
+}
+
+func (c *Cmd) StdoutPipe() (io.Reader, error) {
+	cmd, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	return cmd.StdoutPipe()
+}
+
+func (c *Cmd) StderrPipe() (io.Reader, error) {
+	cmd, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	return cmd.StderrPipe()
+}
+
+func (c *Cmd) Start() error {
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	default:
+	}
+
+	cmd, err := c.build()
+	if err != nil {
+		return err
+	}
+	return cmd.Start()
+}
+
+func (c *Cmd) Wait() error {
+	if c.cmd == nil {
+		return errors.New("exec: not started")
+	}
+	return c.cmd.Wait()
+}
+
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	cmd, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+	return cmd.CombinedOutput()
+}
+
+// Close kills the command if it is still running
+func (c *Cmd) Close() error {
+	if c.cmd == nil || c.cmd.Process == nil {
+		return nil
+	}
+	err := c.cmd.Process.Kill()
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+	return err
+}
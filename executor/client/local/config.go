@@ -0,0 +1,65 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package local
+
+import (
+	"os/user"
+	"runtime"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultShell        = "/bin/sh"
+	DefaultWindowsShell = "cmd.exe"
+)
+
+type Config struct {
+	// Shell runs the commands, defaults to /bin/sh, cmd.exe on windows
+	Shell  string
+	Logger *zap.Logger
+}
+
+func NewConfig(lg *zap.Logger) *Config {
+	cfg := &Config{
+		Logger: lg,
+	}
+	return cfg
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	if cfg.Shell == "" {
+		cfg.Shell = DefaultShell
+		if runtime.GOOS == "windows" {
+			cfg.Shell = DefaultWindowsShell
+		}
+	}
+	return nil
+}
+
+// CurrentUser returns the name of user who runs the commands
+func CurrentUser() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return current.Username
+}
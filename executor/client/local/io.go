@@ -0,0 +1,140 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+// copyFile copies the file src to dst, the mode of file is kept
+func copyFile(ctx context.Context, src, dst string, buf []byte, fn client.IOTraceFn) (written int64, err error) {
+	reader, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	stat, err := reader.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	writer, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stat.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer writer.Close()
+	_ = writer.Chmod(stat.Mode().Perm())
+
+	var trace *client.IOTrace
+	if fn != nil {
+		trace = &client.IOTrace{
+			Name:  filepath.Base(src),
+			Src:   src,
+			Dst:   dst,
+			Total: stat.Size(),
+		}
+	}
+
+	return fcopy(ctx, reader, writer, trace, buf, fn)
+}
+
+// copyDir copies the files in directory src to dst, the directories are created
+func copyDir(ctx context.Context, src, dst string, buf []byte, fn client.IOTraceFn) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		sub, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dst, sub)
+		if info.IsDir() {
+			return os.MkdirAll(dest, os.ModePerm)
+		}
+		_, err = copyFile(ctx, path, dest, buf, fn)
+		return err
+	})
+}
+
+func fcopy(
+	ctx context.Context,
+	reader io.Reader,
+	writer io.Writer,
+	trace *client.IOTrace,
+	buf []byte,
+	fn client.IOTraceFn,
+) (written int64, err error) {
+	if buf == nil {
+		buf = make([]byte, 32*1024)
+	}
+
+	last := time.Now()
+	sub := int64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			err = client.ErrTimeout
+			return
+		default:
+		}
+
+		nr, er := reader.Read(buf)
+		if nr > 0 {
+			nw, ew := writer.Write(buf[0:nr])
+			if nw < 0 || nr < nw {
+				nw = 0
+				if ew == nil {
+					ew = client.ErrInvalidWrite
+				}
+			}
+			written += int64(nw)
+			if ew != nil {
+				err = ew
+				break
+			}
+			if nr != nw {
+				err = io.ErrShortWrite
+				break
+			}
+		}
+		if fn != nil {
+			now := time.Now()
+			trace.Chunk = written
+			trace.Speed = int64(float64(written-sub) / (now.Sub(last).Seconds()))
+			last = now
+			sub = written
+			fn(trace)
+		}
+		if er != nil {
+			if er != io.EOF {
+				err = er
+			}
+			break
+		}
+	}
+	return written, err
+}
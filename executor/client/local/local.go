@@ -0,0 +1,178 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package local
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+// Client runs the commands on the controller itself, the files are copied in local filesystem
+type Client struct {
+	cfg Config
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath(cfg.Shell); err != nil {
+		return nil, errors.Wrap(client.ErrConnect, err.Error())
+	}
+
+	c := &Client{
+		cfg: cfg,
+	}
+	return c, nil
+}
+
+func (c *Client) Name() string {
+	return client.LocalClient
+}
+
+func (c *Client) Stat(ctx context.Context, name string) (*client.Stat, error) {
+	lstat, err := os.Stat(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.Wrapf(client.ErrNotExists, err.Error())
+		}
+		return nil, errors.Wrap(client.ErrRequest, err.Error())
+	}
+
+	stat := &client.Stat{
+		Name:    lstat.Name(),
+		IsDir:   lstat.IsDir(),
+		Mod:     lstat.Mode(),
+		ModTime: lstat.ModTime(),
+		Size:    lstat.Size(),
+	}
+	return stat, nil
+}
+
+func (c *Client) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(client.ErrNotExists, err.Error())
+	}
+	return data, err
+}
+
+func (c *Client) Get(ctx context.Context, src, dst string, opts ...client.GetOption) error {
+	options := client.NewGetOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	stat, err := os.Stat(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(client.ErrNotExists, err.Error())
+	}
+	if err != nil {
+		return errors.Wrap(client.ErrRequest, err.Error())
+	}
+
+	buf := make([]byte, options.CacheSize)
+	if stat.IsDir() {
+		lstat, e1 := os.Stat(dst)
+		if e1 != nil {
+			return e1
+		}
+		if !lstat.IsDir() {
+			return errors.Wrap(client.ErrAlreadyExists, dst)
+		}
+		err = copyDir(ctx, src, dst, buf, options.Trace)
+	} else {
+		lstat, _ := os.Stat(dst)
+		if lstat != nil && lstat.IsDir() {
+			dst = filepath.Join(dst, filepath.Base(src))
+		}
+		_, err = copyFile(ctx, src, dst, buf, options.Trace)
+	}
+	if err != nil {
+		return errors.Wrap(client.ErrRequest, err.Error())
+	}
+
+	return nil
+}
+
+func (c *Client) Put(ctx context.Context, src, dst string, opts ...client.PutOption) error {
+	options := client.NewPutOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	stat, err := os.Stat(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(client.ErrNotExists, err.Error())
+	}
+	if err != nil {
+		return errors.Wrap(client.ErrRequest, err.Error())
+	}
+
+	buf := make([]byte, options.CacheSize)
+	if stat.IsDir() {
+		rstat, _ := os.Stat(dst)
+		if rstat != nil && !rstat.IsDir() {
+			return errors.Wrap(client.ErrAlreadyExists, dst)
+		}
+		err = copyDir(ctx, src, dst, buf, options.Trace)
+	} else {
+		if options.Mkdir {
+			_ = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		} else {
+			rstat, _ := os.Stat(dst)
+			if rstat != nil && rstat.IsDir() {
+				dst = filepath.Join(dst, filepath.Base(src))
+			}
+		}
+		_, err = copyFile(ctx, src, dst, buf, options.Trace)
+	}
+	if err != nil {
+		return errors.Wrap(client.ErrRequest, err.Error())
+	}
+
+	return nil
+}
+
+func (c *Client) Execute(ctx context.Context, shell string, opts ...client.ExecOption) (client.ICmd, error) {
+	options := client.NewExecOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cmd := &Cmd{
+		ctx:    ctx,
+		shell:  c.cfg.Shell,
+		root:   options.Root,
+		name:   shell,
+		args:   options.Args,
+		envs:   options.Environments,
+		become: options.Become,
+	}
+	return cmd, nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
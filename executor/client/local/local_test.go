@@ -0,0 +1,116 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+func newClient(t *testing.T) *Client {
+	if runtime.GOOS == "windows" {
+		t.Skip("the tests use posix shell")
+	}
+	c, err := NewClient(*NewConfig(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClient_Execute(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cmd, err := c.Execute(ctx, "echo",
+		client.ExecWithRootDir(dir),
+		client.ExecWithArgs("$BEE_TEST", "$(pwd)"),
+		client.ExecWithEnv("BEE_TEST", "hello"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := cmd.CombinedOutput()
+	if assert.NoError(t, err) {
+		real, _ := filepath.EvalSymlinks(dir)
+		assert.Contains(t, []string{"hello " + dir + "\n", "hello " + real + "\n"}, string(out))
+	}
+
+	cmd, err = c.Execute(ctx, "exit 3")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Error(t, cmd.Run())
+}
+
+func TestClient_Put_Get(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	src := t.TempDir()
+	if !assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), os.ModePerm)) {
+		return
+	}
+	if !assert.NoError(t, os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("echo ok"), 0o755)) {
+		return
+	}
+
+	traced := int64(0)
+	dst := filepath.Join(t.TempDir(), "modules")
+	err := c.Put(ctx, src, dst, client.PutWithDir(true), client.PutWithTrace(func(trace *client.IOTrace) {
+		traced = trace.Chunk
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(7), traced)
+
+	stat, err := c.Stat(ctx, filepath.Join(dst, "sub", "run.sh"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o755), stat.Mod.Perm())
+	}
+	data, err := c.ReadFile(ctx, filepath.Join(dst, "sub", "run.sh"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "echo ok", string(data))
+	}
+
+	// the file is put into the parent directory which is created
+	file := filepath.Join(t.TempDir(), "bin", "run.sh")
+	if assert.NoError(t, c.Put(ctx, filepath.Join(src, "sub", "run.sh"), file, client.PutWithMkdir(true))) {
+		_, err = os.Stat(file)
+		assert.NoError(t, err)
+	}
+
+	out := t.TempDir()
+	if assert.NoError(t, c.Get(ctx, file, out)) {
+		_, err = os.Stat(filepath.Join(out, "run.sh"))
+		assert.NoError(t, err)
+	}
+
+	_, err = c.Stat(ctx, filepath.Join(dst, "unknown"))
+	assert.ErrorIs(t, err, client.ErrNotExists)
+}
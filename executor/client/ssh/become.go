@@ -0,0 +1,179 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/olive-io/bee/executor/client"
+	bshell "github.com/olive-io/bee/executor/client/internal/shell"
+)
+
+const (
+	DefaultBecomeUser = "root"
+
+	// sudoPrompt is the custom prompt of sudo, it helps to find out the password prompt from stderr
+	sudoPrompt = "[bee-become-password]:"
+	// suPrompt is the suffix of password prompt of su
+	suPrompt = "assword:"
+)
+
+// becomeShell wraps the shell by the become method, returns the prompt of password
+func becomeShell(become *client.Become, shell string, envs map[string]string) (string, string, error) {
+	shell = bshell.Export(shell, envs)
+
+	user := become.User
+	if user == "" {
+		user = DefaultBecomeUser
+	}
+
+	switch become.Method {
+	case "", client.BecomeSudo:
+		text := fmt.Sprintf("sudo -S -p %s -u %s -- /bin/sh -c %s",
+			bshell.Quote(sudoPrompt), bshell.Quote(user), bshell.Quote(shell))
+		return text, sudoPrompt, nil
+	case client.BecomeSu:
+		text := fmt.Sprintf("su %s -c %s", bshell.Quote(user), bshell.Quote(shell))
+		return text, suPrompt, nil
+	default:
+		return "", "", errors.Wrapf(client.ErrNotSupported, "become method '%s'", become.Method)
+	}
+}
+
+// startBecome starts the command as become user, the password prompt is answered over the session stdin
+func (c *Cmd) startBecome() error {
+	become := c.become
+	shell, prompt, err := becomeShell(become, c.shell(), c.envs)
+	if err != nil {
+		return err
+	}
+
+	if become.Method == client.BecomeSu {
+		// su reads the password from terminal only
+		modes := ssh.TerminalModes{ssh.ECHO: 0}
+		if err = c.session.RequestPty("xterm", 40, 200, modes); err != nil {
+			return errors.Wrap(client.ErrRequest, err.Error())
+		}
+	}
+
+	if become.Password != "" {
+		// the prompt of sudo writes to stderr, su writes to terminal (stdout)
+		target := &c.session.Stderr
+		if become.Method == client.BecomeSu {
+			target = &c.session.Stdout
+		}
+
+		if *target != nil {
+			stdin, err := c.session.StdinPipe()
+			if err != nil {
+				return err
+			}
+			pw := newPromptWriter(*target, stdin, prompt, become.Password)
+			*target = pw
+			c.prompts = append(c.prompts, pw)
+		}
+	}
+
+	return c.session.Start(shell)
+}
+
+// promptWriter finds out the password prompt from output and answers it.
+// The output is written line by line, the line of prompt is dropped.
+type promptWriter struct {
+	mu sync.Mutex
+
+	w        io.Writer
+	stdin    io.WriteCloser
+	prompt   []byte
+	password string
+
+	line  []byte
+	asked int
+}
+
+func newPromptWriter(w io.Writer, stdin io.WriteCloser, prompt, password string) *promptWriter {
+	return &promptWriter{
+		w:        w,
+		stdin:    stdin,
+		prompt:   []byte(prompt),
+		password: password,
+	}
+}
+
+func (pw *promptWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.line = append(pw.line, p...)
+	for {
+		idx := bytes.Index(pw.line, pw.prompt)
+		if idx < 0 {
+			break
+		}
+		if nl := bytes.LastIndexByte(pw.line[:idx], '\n'); nl >= 0 {
+			if _, err := pw.w.Write(pw.line[:nl+1]); err != nil {
+				return 0, err
+			}
+		}
+		pw.line = pw.line[idx+len(pw.prompt):]
+		if err := pw.answer(); err != nil {
+			return 0, err
+		}
+	}
+
+	if nl := bytes.LastIndexByte(pw.line, '\n'); nl >= 0 {
+		if _, err := pw.w.Write(pw.line[:nl+1]); err != nil {
+			return 0, err
+		}
+		pw.line = append([]byte{}, pw.line[nl+1:]...)
+	}
+	return len(p), nil
+}
+
+// answer writes the password to stdin at the first time, the prompt asks again
+// only when the password is incorrect, closes stdin to make the command failed.
+func (pw *promptWriter) answer() error {
+	pw.asked += 1
+	switch pw.asked {
+	case 1:
+		_, err := pw.stdin.Write([]byte(pw.password + "\n"))
+		return err
+	case 2:
+		return pw.stdin.Close()
+	}
+	return nil
+}
+
+// Flush writes the rest of output
+func (pw *promptWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.line) == 0 {
+		return nil
+	}
+	_, err := pw.w.Write(pw.line)
+	pw.line = nil
+	return err
+}
@@ -0,0 +1,80 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+type stdinBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *stdinBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestBecomeShell(t *testing.T) {
+	envs := map[string]string{"B": "2", "A": "it's"}
+	shell, prompt, err := becomeShell(&client.Become{}, "cd /tmp; echo ok", envs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, sudoPrompt, prompt)
+	assert.Equal(t, `sudo -S -p '[bee-become-password]:' -u 'root' -- /bin/sh -c 'export A='\''it'\''\'\'''\''s'\''; export B='\''2'\''; cd /tmp; echo ok'`, shell)
+
+	shell, prompt, err = becomeShell(&client.Become{Method: client.BecomeSu, User: "admin"}, "id", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, suPrompt, prompt)
+	assert.Equal(t, `su 'admin' -c 'id'`, shell)
+
+	_, _, err = becomeShell(&client.Become{Method: client.BecomeRunas}, "id", nil)
+	assert.ErrorIs(t, err, client.ErrNotSupported)
+}
+
+func TestPromptWriter(t *testing.T) {
+	var out bytes.Buffer
+	stdin := &stdinBuffer{}
+	pw := newPromptWriter(&out, stdin, sudoPrompt, "secret")
+
+	for _, chunk := range []string{"warning\n[bee-bec", "ome-password]:", "{\"ok\": true}\n", "tail"} {
+		n, err := pw.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.NoError(t, pw.Flush())
+
+	assert.Equal(t, "warning\n{\"ok\": true}\ntail", out.String())
+	assert.Equal(t, "secret\n", stdin.String())
+	assert.False(t, stdin.closed)
+
+	// the prompt asks again when the password is incorrect
+	_, err := pw.Write([]byte(sudoPrompt))
+	assert.NoError(t, err)
+	assert.True(t, stdin.closed)
+	assert.Equal(t, "secret\n", stdin.String())
+}
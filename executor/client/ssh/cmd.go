@@ -26,16 +26,22 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"golang.org/x/crypto/ssh"
+
+	"github.com/olive-io/bee/executor/client"
+	bshell "github.com/olive-io/bee/executor/client/internal/shell"
 )
 
 type Cmd struct {
 	ctx     context.Context
 	session *ssh.Session
 
-	root string
-	name string
-	args []string
-	envs map[string]string
+	root   string
+	name   string
+	args   []string
+	envs   map[string]string
+	become *client.Become
+
+	prompts []*promptWriter
 }
 
 func (c *Cmd) shell() string {
@@ -71,12 +77,12 @@ func (c *Cmd) Start() error {
 	default:
 	}
 
-	shell := c.shell()
-	for key, value := range c.envs {
-		if err := c.session.Setenv(key, value); err != nil {
-			return err
-		}
+	if c.become != nil {
+		return c.startBecome()
 	}
+
+	// exports the variables in shell, sshd accepts few variables by Setenv (AcceptEnv)
+	shell := bshell.Export(c.shell(), c.envs)
 	return c.session.Start(shell)
 }
 
@@ -90,6 +96,11 @@ func (c *Cmd) Wait() error {
 	case <-c.ctx.Done():
 		return c.ctx.Err()
 	case err := <-ech:
+		for _, pw := range c.prompts {
+			if e1 := pw.Flush(); e1 != nil && err == nil {
+				err = e1
+			}
+		}
 		return err
 	}
 }
@@ -19,6 +19,7 @@ package ssh
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"go.uber.org/zap"
@@ -32,12 +33,19 @@ const (
 	DefaultPort = 22
 )
 
+// Dialer dials the address of ssh server, e.g. *Client dials through the bastion
+type Dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
 type Config struct {
 	Network string
 	Addr    string
 
 	ClientConfig *ssh.ClientConfig
-	Logger       *zap.Logger
+	// Dialer dials Addr instead of connecting it directly, e.g. through the bastion
+	Dialer Dialer
+	Logger *zap.Logger
 }
 
 func NewAuthConfig(lg *zap.Logger, host, user, password string) *Config {
@@ -0,0 +1,302 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// HostKeyStrict rejects the hosts whose keys aren't known
+	HostKeyStrict = "strict"
+	// HostKeyAcceptNew accepts and records the keys of new hosts, the changed keys are rejected
+	HostKeyAcceptNew = "accept-new"
+	// HostKeyOff doesn't check the host keys
+	HostKeyOff = "off"
+)
+
+// DefaultHostKeyChecking is the mode of host key checking if it isn't set
+const DefaultHostKeyChecking = HostKeyAcceptNew
+
+var (
+	ErrUnknownHostKey = errors.New("unknown host key")
+	ErrRevokedHostKey = errors.New("revoked host key")
+)
+
+// HostKeyChangedError is returned when the key of host doesn't match the known ones,
+// it may be a man-in-the-middle attack or the host is reinstalled.
+type HostKeyChangedError struct {
+	Host string
+	Key  ssh.PublicKey
+	// Want are the known keys of host, the line numbers are 0 for the pinned keys
+	Want []knownhosts.KnownKey
+}
+
+func (e *HostKeyChangedError) Error() string {
+	known := make([]string, 0, len(e.Want))
+	for _, want := range e.Want {
+		if want.Filename == "" {
+			known = append(known, ssh.FingerprintSHA256(want.Key)+" (pinned)")
+			continue
+		}
+		known = append(known, fmt.Sprintf("%s (%s:%d)", ssh.FingerprintSHA256(want.Key), want.Filename, want.Line))
+	}
+	return fmt.Sprintf("host key of %s changed: got %s, want %s",
+		e.Host, ssh.FingerprintSHA256(e.Key), strings.Join(known, ", "))
+}
+
+// knownHostsMu serializes the reading and writing of known_hosts files
+var knownHostsMu sync.Mutex
+
+// HostKeyChecker verifies the host keys by the pinned keys or the known_hosts files
+type HostKeyChecker struct {
+	// Mode is one of HostKeyStrict, HostKeyAcceptNew and HostKeyOff
+	Mode string
+	// KnownHosts are the known_hosts files in OpenSSH format, the keys accepted
+	// by HostKeyAcceptNew are appended to the first one.
+	KnownHosts []string
+	// Pinned are the keys of host, they take precedence over KnownHosts
+	Pinned []ssh.PublicKey
+	Logger *zap.Logger
+}
+
+// ParseHostKeys parses the keys in authorized_keys format separated by ',',
+// e.g. "ssh-ed25519 AAAAC3Nza...".
+func ParseHostKeys(text string) ([]ssh.PublicKey, error) {
+	keys := make([]ssh.PublicKey, 0)
+	for _, line := range strings.Split(text, ",") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse host key %q", line)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Callback returns the ssh.HostKeyCallback of the mode
+func (hc *HostKeyChecker) Callback() (ssh.HostKeyCallback, error) {
+	switch hc.Mode {
+	case HostKeyOff:
+		return ssh.InsecureIgnoreHostKey(), nil
+	case HostKeyStrict, HostKeyAcceptNew:
+	case "":
+		hc.Mode = DefaultHostKeyChecking
+	default:
+		return nil, fmt.Errorf("invalid host key checking '%s'", hc.Mode)
+	}
+	if hc.Logger == nil {
+		hc.Logger = zap.NewNop()
+	}
+	return hc.check, nil
+}
+
+func (hc *HostKeyChecker) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if len(hc.Pinned) != 0 {
+		return hc.checkPinned(hostname, key)
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	callback, err := hc.loadKnownHosts()
+	if err != nil {
+		return err
+	}
+	if callback != nil {
+		err = callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		var revokedErr *knownhosts.RevokedError
+		switch {
+		case errors.As(err, &keyErr) && len(keyErr.Want) != 0:
+			return &HostKeyChangedError{Host: hostname, Key: key, Want: keyErr.Want}
+		case errors.As(err, &revokedErr):
+			return errors.Wrapf(ErrRevokedHostKey, "%s %s", hostname, ssh.FingerprintSHA256(key))
+		case !errors.As(err, &keyErr):
+			return err
+		}
+	}
+
+	if hc.Mode != HostKeyAcceptNew || len(hc.KnownHosts) == 0 {
+		return errors.Wrapf(ErrUnknownHostKey, "%s %s", hostname, ssh.FingerprintSHA256(key))
+	}
+	return hc.accept(hc.KnownHosts[0], hostname, remote, key)
+}
+
+// Algorithms returns the host key algorithms of the known keys of host, which is "host:port"
+// passed to ssh.Dial. The server offers the key of the known type first, as OpenSSH does.
+// It returns nil if the host is unknown, the default algorithms are used.
+func (hc *HostKeyChecker) Algorithms(hostname string) ([]string, error) {
+	if hc.Mode == HostKeyOff {
+		return nil, nil
+	}
+
+	keys := hc.Pinned
+	if len(keys) == 0 {
+		knownHostsMu.Lock()
+		callback, err := hc.loadKnownHosts()
+		if err == nil && callback != nil {
+			// the key of unknown type returns all known keys of host
+			err = callback(hostname, &net.TCPAddr{IP: net.IPv4zero}, probeKey{})
+		}
+		knownHostsMu.Unlock()
+
+		var keyErr *knownhosts.KeyError
+		switch {
+		case err == nil:
+		case errors.As(err, &keyErr):
+			// the known keys are in the order of files and lines of known_hosts
+			want := keyErr.Want
+			sort.SliceStable(want, func(i, j int) bool {
+				fi := lo.IndexOf[string](hc.KnownHosts, want[i].Filename)
+				fj := lo.IndexOf[string](hc.KnownHosts, want[j].Filename)
+				if fi != fj {
+					return fi < fj
+				}
+				return want[i].Line < want[j].Line
+			})
+			for _, known := range want {
+				keys = append(keys, known.Key)
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	algorithms := make([]string, 0, len(keys))
+	seen := map[string]struct{}{}
+	for _, key := range keys {
+		for _, algorithm := range keyAlgorithms(key.Type()) {
+			if _, ok := seen[algorithm]; ok {
+				continue
+			}
+			seen[algorithm] = struct{}{}
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	if len(algorithms) == 0 {
+		return nil, nil
+	}
+	return algorithms, nil
+}
+
+// keyAlgorithms returns the signature algorithms of the type of key, the rsa keys sign by sha2 first
+func keyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// probeKey is the key of unknown type, knownhosts returns the known keys of host for it
+type probeKey struct{}
+
+func (probeKey) Type() string { return "bee-probe" }
+
+func (probeKey) Marshal() []byte { return []byte("bee-probe") }
+
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error {
+	return errors.New("probe key can't verify")
+}
+
+// loadKnownHosts returns the callback of the existing known_hosts files, it returns nil
+// if there is no file. The caller holds knownHostsMu.
+func (hc *HostKeyChecker) loadKnownHosts() (ssh.HostKeyCallback, error) {
+	files := make([]string, 0, len(hc.KnownHosts))
+	for _, file := range hc.KnownHosts {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, errors.Wrap(err, "load known_hosts")
+	}
+	return callback, nil
+}
+
+func (hc *HostKeyChecker) checkPinned(hostname string, key ssh.PublicKey) error {
+	marshaled := key.Marshal()
+	want := make([]knownhosts.KnownKey, 0, len(hc.Pinned))
+	for _, pinned := range hc.Pinned {
+		if string(pinned.Marshal()) == string(marshaled) {
+			return nil
+		}
+		want = append(want, knownhosts.KnownKey{Key: pinned})
+	}
+	return &HostKeyChangedError{Host: hostname, Key: key, Want: want}
+}
+
+// accept appends the key of new host to known_hosts file
+func (hc *HostKeyChecker) accept(file, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	addresses := []string{knownhosts.Normalize(hostname)}
+	// the remote of host behind the bastion is unspecified
+	if tcpAddr, ok := remote.(*net.TCPAddr); ok && !tcpAddr.IP.IsUnspecified() {
+		if ip := knownhosts.Normalize(remote.String()); ip != addresses[0] {
+			addresses = append(addresses, ip)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return errors.Wrap(err, "create known_hosts")
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "open known_hosts")
+	}
+	defer f.Close()
+	if _, err = f.WriteString(knownhosts.Line(addresses, key) + "\n"); err != nil {
+		return errors.Wrap(err, "write known_hosts")
+	}
+
+	hc.Logger.Info("accept new host key",
+		zap.String("host", hostname),
+		zap.String("fingerprint", ssh.FingerprintSHA256(key)),
+		zap.String("known_hosts", file))
+	return nil
+}
+
+// IsHostKeyError returns true if err is returned by HostKeyChecker
+func IsHostKeyError(err error) bool {
+	var changed *HostKeyChangedError
+	return errors.As(err, &changed) || errors.Is(err, ErrUnknownHostKey) || errors.Is(err, ErrRevokedHostKey)
+}
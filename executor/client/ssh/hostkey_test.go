@@ -0,0 +1,178 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyChecker_AcceptNew(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	checker := &HostKeyChecker{Mode: HostKeyAcceptNew, KnownHosts: []string{file}}
+	callback, err := checker.Callback()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.2.32"), Port: 22}
+	key := newHostKey(t)
+	assert.NoError(t, callback("web1:22", remote, key))
+
+	data, err := os.ReadFile(file)
+	if assert.NoError(t, err) {
+		assert.True(t, strings.HasPrefix(string(data), "web1,192.168.2.32 ssh-ed25519 "))
+	}
+	// the recorded key is known
+	assert.NoError(t, callback("web1:22", remote, key))
+
+	err = callback("web1:22", remote, newHostKey(t))
+	var changed *HostKeyChangedError
+	if assert.True(t, errors.As(err, &changed)) {
+		assert.Equal(t, "web1:22", changed.Host)
+		assert.Len(t, changed.Want, 1)
+	}
+	assert.True(t, IsHostKeyError(err))
+}
+
+func TestHostKeyChecker_Strict(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	checker := &HostKeyChecker{Mode: HostKeyStrict, KnownHosts: []string{file}}
+	callback, err := checker.Callback()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.2.32"), Port: 2222}
+	key := newHostKey(t)
+	err = callback("web1:2222", remote, key)
+	assert.ErrorIs(t, err, ErrUnknownHostKey)
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+
+	line := "[web1]:2222 " + string(ssh.MarshalAuthorizedKey(key))
+	assert.NoError(t, os.WriteFile(file, []byte(line), 0600))
+	assert.NoError(t, callback("web1:2222", remote, key))
+}
+
+func TestHostKeyChecker_Pinned(t *testing.T) {
+	key := newHostKey(t)
+	pinned, err := ParseHostKeys(strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	checker := &HostKeyChecker{Mode: HostKeyStrict, Pinned: pinned}
+	callback, err := checker.Callback()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.2.32"), Port: 22}
+	assert.NoError(t, callback("web1:22", remote, key))
+
+	err = callback("web1:22", remote, newHostKey(t))
+	var changed *HostKeyChangedError
+	assert.True(t, errors.As(err, &changed))
+	assert.Contains(t, err.Error(), "(pinned)")
+}
+
+func TestHostKeyChecker_Mode(t *testing.T) {
+	checker := &HostKeyChecker{Mode: HostKeyOff}
+	callback, err := checker.Callback()
+	if assert.NoError(t, err) {
+		assert.NoError(t, callback("web1:22", nil, newHostKey(t)))
+	}
+
+	checker = &HostKeyChecker{Mode: "yes"}
+	_, err = checker.Callback()
+	assert.Error(t, err)
+}
+
+func TestHostKeyChecker_Algorithms(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := ssh.NewPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := newHostKey(t)
+
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	lines := []string{
+		knownhosts.Line([]string{"web1"}, rsaKey),
+		knownhosts.Line([]string{"web1"}, edKey),
+		knownhosts.Line([]string{"[web2]:2222"}, edKey),
+	}
+	if err = os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := &HostKeyChecker{Mode: HostKeyStrict, KnownHosts: []string{file}}
+	algorithms, err := checker.Algorithms("web1:22")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA, ssh.KeyAlgoED25519}, algorithms)
+	}
+	algorithms, err = checker.Algorithms("web2:2222")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{ssh.KeyAlgoED25519}, algorithms)
+	}
+	// the unknown host uses the default algorithms
+	algorithms, err = checker.Algorithms("web3:22")
+	if assert.NoError(t, err) {
+		assert.Nil(t, algorithms)
+	}
+
+	// the pinned keys take precedence over known_hosts
+	checker.Pinned = []ssh.PublicKey{edKey}
+	algorithms, err = checker.Algorithms("web1:22")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{ssh.KeyAlgoED25519}, algorithms)
+	}
+
+	checker.Mode = HostKeyOff
+	algorithms, err = checker.Algorithms("web1:22")
+	if assert.NoError(t, err) {
+		assert.Nil(t, algorithms)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"io"
 	"io/fs"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
@@ -33,6 +34,10 @@ import (
 	"github.com/olive-io/bee/executor/client"
 )
 
+// keepaliveRequest is the global request of OpenSSH keepalive, the servers reply to it
+// even if they don't support it.
+const keepaliveRequest = "keepalive@openssh.com"
+
 type Client struct {
 	cfg Config
 
@@ -62,13 +67,42 @@ func (c *Client) dial() (*ssh.Client, error) {
 	addr := c.cfg.Addr
 	ccfg := c.cfg.ClientConfig
 
-	sc, err := ssh.Dial(network, addr, ccfg)
+	var sc *ssh.Client
+	var err error
+	if dialer := c.cfg.Dialer; dialer != nil {
+		sc, err = dialThrough(dialer, network, addr, ccfg)
+	} else {
+		sc, err = ssh.Dial(network, addr, ccfg)
+	}
 	if err != nil {
+		if IsHostKeyError(err) {
+			// keeps the error of host key, e.g. HostKeyChangedError
+			return nil, errors.Mark(err, client.ErrConnect)
+		}
 		return nil, errors.Wrap(client.ErrConnect, err.Error())
 	}
 	return sc, nil
 }
 
+// dialThrough connects the ssh server by the connection of dialer
+func dialThrough(dialer Dialer, network, addr string, ccfg *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := dialer.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	sconn, chans, reqs, err := ssh.NewClientConn(conn, addr, ccfg)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sconn, chans, reqs), nil
+}
+
+// Dial connects addr from the remote host, the client is the bastion of the others
+func (c *Client) Dial(network, addr string) (net.Conn, error) {
+	return c.sc.Dial(network, addr)
+}
+
 func (c *Client) newSFTPSession() (*sftp.Client, error) {
 	copts := []sftp.ClientOption{}
 	sfc, err := sftp.NewClient(c.sc, copts...)
@@ -260,11 +294,32 @@ func (c *Client) Execute(ctx context.Context, shell string, opts ...client.ExecO
 		name:    shell,
 		args:    options.Args,
 		envs:    options.Environments,
+		become:  options.Become,
 	}
 
 	return cmd, nil
 }
 
+// Ping sends the keepalive request over the connection, the connection is broken
+// if the request fails or the server doesn't reply in time.
+func (c *Client) Ping(ctx context.Context) error {
+	ech := make(chan error, 1)
+	go func() {
+		_, _, err := c.sc.SendRequest(keepaliveRequest, true, nil)
+		ech <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(client.ErrConnect, "keepalive timeout")
+	case err := <-ech:
+		if err != nil {
+			return errors.Wrap(client.ErrConnect, err.Error())
+		}
+		return nil
+	}
+}
+
 func (c *Client) Close() error {
 	if err := c.sc.Close(); err != nil {
 		return err
@@ -0,0 +1,76 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package winrm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+// becomeShell wraps the powershell script to run as become user by the credential of it,
+// the script reads the password from the first line of stdin, see becomeInput.
+func becomeShell(become *client.Become, shell string) (string, error) {
+	switch become.Method {
+	case "", client.BecomeRunas:
+	default:
+		return "", errors.Wrapf(client.ErrNotSupported, "become method '%s'", become.Method)
+	}
+	if become.User == "" {
+		return "", errors.Wrapf(client.ErrNotSupported, "runas without user")
+	}
+
+	text := fmt.Sprintf("$bp = ConvertTo-SecureString ([Console]::In.ReadLine()) -AsPlainText -Force; "+
+		"$bc = New-Object System.Management.Automation.PSCredential(%s, $bp); "+
+		"Invoke-Command -ComputerName localhost -Credential $bc -ScriptBlock { %s }",
+		psQuote(become.User), shell)
+	return text, nil
+}
+
+// becomeInput returns the stdin of the script of becomeShell, the password of become user
+// is written in the first line, so it never appears in the command line of process.
+func becomeInput(become *client.Become, stdin io.Reader) io.Reader {
+	password := strings.NewReader(become.Password + "\r\n")
+	if stdin == nil {
+		return password
+	}
+	return io.MultiReader(password, stdin)
+}
+
+// envShell sets the environment variables of process before the powershell script
+func envShell(shell string, envs map[string]string) string {
+	keys := make([]string, 0, len(envs))
+	for key := range envs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i := len(keys) - 1; i >= 0; i-- {
+		shell = "$env:" + keys[i] + " = " + psQuote(envs[keys[i]]) + "; " + shell
+	}
+	return shell
+}
+
+// psQuote quotes s as a powershell literal string
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
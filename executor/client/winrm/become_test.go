@@ -0,0 +1,68 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package winrm
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+func Test_becomeShell(t *testing.T) {
+	become := &client.Become{User: "Administrator", Password: `it's "$x"`}
+	shell, err := becomeShell(become, "Get-Date")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotContains(t, shell, "it's")
+	assert.Contains(t, shell, "ConvertTo-SecureString ([Console]::In.ReadLine()) -AsPlainText -Force")
+	assert.Contains(t, shell, "PSCredential('Administrator', $bp)")
+	assert.Contains(t, shell, "-ScriptBlock { Get-Date }")
+
+	_, err = becomeShell(&client.Become{Method: client.BecomeRunas}, "Get-Date")
+	assert.ErrorIs(t, err, client.ErrNotSupported)
+
+	_, err = becomeShell(&client.Become{Method: client.BecomeSudo, User: "root"}, "Get-Date")
+	assert.ErrorIs(t, err, client.ErrNotSupported)
+}
+
+func Test_becomeInput(t *testing.T) {
+	become := &client.Become{User: "Administrator", Password: `it's "$x"`}
+	data, err := io.ReadAll(becomeInput(become, nil))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "it's \"$x\"\r\n", string(data))
+
+	data, err = io.ReadAll(becomeInput(become, strings.NewReader("input")))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "it's \"$x\"\r\ninput", string(data))
+}
+
+func Test_envShell(t *testing.T) {
+	shell := envShell("Get-Date", map[string]string{"B": "2", "A": "it's"})
+	assert.Equal(t, "$env:A = 'it''s'; $env:B = '2'; Get-Date", shell)
+
+	assert.Equal(t, "Get-Date", envShell("Get-Date", nil))
+}
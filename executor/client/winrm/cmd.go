@@ -40,10 +40,11 @@ var (
 type Cmd struct {
 	ctx context.Context
 
-	root string
-	name string
-	args []string
-	envs map[string]string
+	root   string
+	name   string
+	args   []string
+	envs   map[string]string
+	become *client.Become
 
 	s      *winrm.Shell
 	c      *winrm.Command
@@ -116,7 +117,15 @@ func (c *Cmd) Start() error {
 	}
 	args = append(args, c.name)
 	args = append(args, c.args...)
-	shell := strings.Join(args, " ")
+	shell := envShell(strings.Join(args, " "), c.envs)
+	stdin := c.stdin
+	if c.become != nil {
+		var err error
+		if shell, err = becomeShell(c.become, shell); err != nil {
+			return err
+		}
+		stdin = becomeInput(c.become, stdin)
+	}
 	cc, err := c.s.ExecuteWithContext(ctx, fmt.Sprintf(`powershell -c "%s"`, shell))
 	if err != nil {
 		return errors.Wrapf(client.ErrRequest, err.Error())
@@ -132,7 +141,7 @@ func (c *Cmd) Start() error {
 
 	c.wg.Add(3)
 	go func() {
-		if c.stdin == nil {
+		if stdin == nil {
 			c.wg.Done()
 			return
 		}
@@ -141,7 +150,7 @@ func (c *Cmd) Start() error {
 			cc.Stdin.Close()
 			c.wg.Done()
 		}()
-		io.Copy(cc.Stdin, c.stdin)
+		io.Copy(cc.Stdin, stdin)
 	}()
 	go func() {
 		defer c.wg.Done()
@@ -206,6 +206,7 @@ func (wr *WinRM) Execute(ctx context.Context, shell string, opts ...client.ExecO
 		name:          shell,
 		args:          options.Args,
 		envs:          options.Environments,
+		become:        options.Become,
 		s:             bash,
 		childIOFiles:  make([]io.Closer, 0),
 		parentIOPipes: make([]io.Closer, 0),
@@ -31,6 +31,7 @@ import (
 
 	"github.com/olive-io/bee/executor/client"
 	"github.com/olive-io/bee/executor/client/grpc"
+	"github.com/olive-io/bee/executor/client/local"
 	"github.com/olive-io/bee/executor/client/ssh"
 	"github.com/olive-io/bee/executor/client/winrm"
 	"github.com/olive-io/bee/parser"
@@ -38,7 +39,37 @@ import (
 	"github.com/olive-io/bee/vars"
 )
 
-func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
+// buildSSHClient connects host by ssh, the connection goes through the bastions of
+// bee_ssh_proxy_jump which are shared by the hosts, see acquireJump.
+func (e *Executor) buildSSHClient(host *parser.Host, options *ClientOptions) (client.IClient, error) {
+	v := host.Vars[vars.BeeSSHProxyJumpVars]
+	if v == "" {
+		sc, err := e.dialSSH(host, options, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		return sc, nil
+	}
+
+	hops, err := parseProxyJump(v)
+	if err != nil {
+		return nil, err
+	}
+	jump, err := e.acquireJump(hops)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := e.dialSSH(host, options, 0, jump.cc)
+	if err != nil {
+		e.releaseJump(jump)
+		return nil, err
+	}
+	return &jumpedClient{Client: sc, release: func() { e.releaseJump(jump) }}, nil
+}
+
+// dialSSH connects host by ssh, port overrides the port of host if it isn't 0
+// and dialer connects the host through the bastion if it isn't nil.
+func (e *Executor) dialSSH(host *parser.Host, options *ClientOptions, port int, dialer ssh.Dialer) (*ssh.Client, error) {
 	lg := e.lg
 	ch, name := host.Name, host.Name
 	variables := host.Vars
@@ -47,10 +78,12 @@ func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
 	}
 	ch, _, _ = strings.Cut(ch, ":")
 
-	port := ssh.DefaultPort
-	if val, ok := variables[vars.BeePortVars]; ok {
-		if i, _ := strconv.ParseInt(val, 10, 64); i > 0 {
-			port = int(i)
+	if port == 0 {
+		port = ssh.DefaultPort
+		if val, ok := variables[vars.BeePortVars]; ok {
+			if i, _ := strconv.ParseInt(val, 10, 64); i > 0 {
+				port = int(i)
+			}
 		}
 	}
 	addr := fmt.Sprintf("%s:%d", ch, port)
@@ -58,6 +91,13 @@ func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
 	if val, ok := variables[vars.BeeUserVars]; ok {
 		user = val
 	}
+	// the password of remote user is saved as <user>@<host>
+	passwdKey := name
+	remote := options.User != "" && options.User != user
+	if remote {
+		user = options.User
+		passwdKey = user + "@" + name
+	}
 
 	lfields := []zap.Field{
 		zap.String("client", "ssh"),
@@ -67,10 +107,10 @@ func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
 
 	authMethods := make([]cssh.AuthMethod, 0)
 
-	passwd, _ := e.passwords.GetRawPassword(name, secret.WithNamespace("ssh"))
+	passwd, _ := e.passwords.GetRawPassword(passwdKey, secret.WithNamespace("ssh"))
 	if passwd != "" {
 		authMethods = append(authMethods, cssh.Password(passwd))
-	} else if v, ok := variables[vars.BeeSSHPasswdVars]; ok {
+	} else if v, ok := variables[vars.BeeSSHPasswdVars]; ok && !remote {
 		authMethods = append(authMethods, cssh.Password(v))
 	}
 
@@ -100,18 +140,34 @@ func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
 		authMethods = append(authMethods, cssh.PublicKeys(signer))
 	}
 
+	checker, err := e.hostKeyChecker(host)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := checker.Callback()
+	if err != nil {
+		return nil, err
+	}
+	// the server offers the key of the known type, otherwise it may be rejected as a changed one
+	hostKeyAlgorithms, err := checker.Algorithms(addr)
+	if err != nil {
+		return nil, err
+	}
+
 	ccfg := &cssh.ClientConfig{
-		Config:          cssh.Config{},
-		User:            user,
-		Auth:            authMethods,
-		HostKeyCallback: cssh.InsecureIgnoreHostKey(),
-		Timeout:         client.DefaultDialTimeout,
+		Config:            cssh.Config{},
+		User:              user,
+		Auth:              authMethods,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		Timeout:           client.DefaultDialTimeout,
 	}
 
 	scfg := ssh.Config{
 		Network:      "tcp",
 		Addr:         addr,
 		ClientConfig: ccfg,
+		Dialer:       dialer,
 		Logger:       lg,
 	}
 
@@ -129,7 +185,39 @@ func (e *Executor) buildSSHClient(host *parser.Host) (*ssh.Client, error) {
 	return sc, nil
 }
 
-func (e *Executor) buildWinRMClient(host *parser.Host) (*winrm.WinRM, error) {
+// hostKeyChecker returns the checker of ssh host key of host, bee_ssh_host_key pins the keys of host,
+// bee_ssh_host_key_checking and bee_ssh_known_hosts override the default ones of Executor.
+func (e *Executor) hostKeyChecker(host *parser.Host) (*ssh.HostKeyChecker, error) {
+	variables := host.Vars
+	checker := &ssh.HostKeyChecker{
+		Mode:       e.opts.HostKeyChecking,
+		KnownHosts: e.opts.KnownHosts,
+		Logger:     e.lg,
+	}
+	if v := variables[vars.BeeSSHHostKeyCheckingVars]; v != "" {
+		checker.Mode = v
+	}
+	if v := variables[vars.BeeSSHKnownHostsVars]; v != "" {
+		checker.KnownHosts = strings.Split(v, ",")
+	} else if len(checker.KnownHosts) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.Wrap(err, "load home dir")
+		}
+		checker.KnownHosts = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+	if v := variables[vars.BeeSSHHostKeyVars]; v != "" {
+		keys, err := ssh.ParseHostKeys(v)
+		if err != nil {
+			return nil, err
+		}
+		checker.Pinned = keys
+	}
+
+	return checker, nil
+}
+
+func (e *Executor) buildWinRMClient(host *parser.Host, options *ClientOptions) (*winrm.WinRM, error) {
 	lg := e.lg
 
 	ch, name := host.Name, host.Name
@@ -151,6 +239,12 @@ func (e *Executor) buildWinRMClient(host *parser.Host) (*winrm.WinRM, error) {
 	if val, ok := variables[vars.BeeUserVars]; ok {
 		user = val
 	}
+	passwdKey := name
+	remote := options.User != "" && options.User != user
+	if remote {
+		user = options.User
+		passwdKey = user + "@" + name
+	}
 
 	lfields := []zap.Field{
 		zap.String("client", "winrm"),
@@ -158,8 +252,8 @@ func (e *Executor) buildWinRMClient(host *parser.Host) (*winrm.WinRM, error) {
 		zap.String("addr", addr),
 		zap.String("user", user)}
 
-	passwd, err := e.passwords.GetRawPassword(name, secret.WithNamespace("ssh"))
-	if v, ok := variables[vars.BeeWMPasswdVars]; ok {
+	passwd, err := e.passwords.GetRawPassword(passwdKey, secret.WithNamespace("ssh"))
+	if v, ok := variables[vars.BeeWMPasswdVars]; ok && !remote {
 		passwd = v
 	}
 
@@ -230,3 +324,18 @@ func (e *Executor) buildGRPCClient(host *parser.Host) (*grpc.Client, error) {
 	}
 	return cc, nil
 }
+
+func (e *Executor) buildLocalClient(host *parser.Host) (*local.Client, error) {
+	lg := e.lg
+
+	lcfg := local.NewConfig(lg)
+	if err := lcfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	lg.Debug("create new bee connection",
+		zap.String("client", "local"),
+		zap.String("name", host.Name))
+
+	return local.NewClient(*lcfg)
+}
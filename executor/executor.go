@@ -18,127 +18,508 @@
 package executor
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 
 	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/executor/client/local"
+	"github.com/olive-io/bee/executor/client/ssh"
+	"github.com/olive-io/bee/executor/client/winrm"
 	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
 	"github.com/olive-io/bee/secret"
 	"github.com/olive-io/bee/vars"
 )
 
+const (
+	// DefaultIdleTTL is the max idle time of cached clients, the idle clients are closed
+	DefaultIdleTTL = time.Minute * 5
+	// DefaultPingTimeout is the timeout of health check of cached clients
+	DefaultPingTimeout = time.Second * 10
+)
+
 var (
 	ErrHostNotExists = errors.New("host not exists")
 	ErrInvalidClient = errors.New("invalid client kind")
 )
 
+type Options struct {
+	// IdleTTL is the max idle time of cached clients, the clients are kept until Cleanup if it is 0
+	IdleTTL time.Duration
+	// PingTimeout is the timeout of health check before reusing the client, see client.IHealthChecker
+	PingTimeout time.Duration
+	// ProbeTimeout is the timeout of probing each port of smart connection, see client.SmartClient
+	ProbeTimeout time.Duration
+	// HostKeyChecking is the default mode of checking the ssh host keys, see ssh.HostKeyChecker
+	HostKeyChecking string
+	// KnownHosts are the default known_hosts files, the new host keys are recorded in the first one
+	KnownHosts []string
+}
+
+type Option func(*Options)
+
+// WithIdleTTL closes the cached clients which are idle for ttl
+func WithIdleTTL(ttl time.Duration) Option {
+	return func(options *Options) {
+		options.IdleTTL = ttl
+	}
+}
+
+// WithPingTimeout sets the timeout of health check of cached clients
+func WithPingTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.PingTimeout = timeout
+	}
+}
+
+// WithProbeTimeout sets the timeout of probing each port of smart connection
+func WithProbeTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.ProbeTimeout = timeout
+	}
+}
+
+// WithHostKeyChecking sets the default mode of checking the ssh host keys,
+// it is overridden by bee_ssh_host_key_checking of host.
+func WithHostKeyChecking(mode string) Option {
+	return func(options *Options) {
+		options.HostKeyChecking = mode
+	}
+}
+
+// WithKnownHosts sets the default known_hosts files, it is overridden by bee_ssh_known_hosts of host
+func WithKnownHosts(files ...string) Option {
+	return func(options *Options) {
+		options.KnownHosts = files
+	}
+}
+
+// cachedClient is the client in cache, it is closed when it is removed from
+// cache and released by all callers.
+type cachedClient struct {
+	key string
+	cc  client.IClient
+	// refs is the number of callers which use the client, see ReleaseClient
+	refs     int
+	lastUsed time.Time
+	removed  bool
+}
+
+// Executor keeps the clients of hosts alive across runs. The cached client is
+// checked before reused and it is built again when the connection is broken,
+// the clients which are idle for IdleTTL are closed.
 type Executor struct {
 	lg *zap.Logger
 
+	opts *Options
+
 	inventory *inv.Manager
 	passwords *secret.PasswordManager
 
-	cmu     sync.RWMutex
-	clients map[string]client.IClient
+	cmu     sync.Mutex
+	clients map[string]*cachedClient
+	// used are the clients which are not released, including the removed ones
+	used map[client.IClient]*cachedClient
+	// dial builds the client of host, it is newClient except in tests
+	dial func(name string, options *ClientOptions) (client.IClient, error)
+
+	kmu sync.Mutex
+	// kinds are the resolved smart connections of hosts
+	kinds map[string]string
+
+	// jmu guards jumps and jlocks only, the bastions are connected under the locks of chains
+	jmu sync.Mutex
+	// jumps are the bastions of bee_ssh_proxy_jump, key is the chain of hops
+	jumps map[string]*bastion
+	// jlocks are the locks of chains which are connecting, see lockJump
+	jlocks map[string]*jumpLock
+
+	stopOnce sync.Once
+	stopping chan struct{}
 }
 
-func NewExecutor(lg *zap.Logger, inventory *inv.Manager, passwords *secret.PasswordManager) *Executor {
+func NewExecutor(lg *zap.Logger, inventory *inv.Manager, passwords *secret.PasswordManager, opts ...Option) *Executor {
+	options := &Options{
+		IdleTTL:      DefaultIdleTTL,
+		PingTimeout:  DefaultPingTimeout,
+		ProbeTimeout: DefaultProbeTimeout,
+
+		HostKeyChecking: ssh.DefaultHostKeyChecking,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	executor := &Executor{
 		lg:        lg,
+		opts:      options,
 		inventory: inventory,
 		passwords: passwords,
-		clients:   map[string]client.IClient{},
+		clients:   map[string]*cachedClient{},
+		used:      map[client.IClient]*cachedClient{},
+		kinds:     map[string]string{},
+		jumps:     map[string]*bastion{},
+		jlocks:    map[string]*jumpLock{},
+		stopping:  make(chan struct{}),
+	}
+	executor.dial = executor.newClient
+	if options.IdleTTL > 0 {
+		go executor.expireLoop(options.IdleTTL)
 	}
 	return executor
 }
 
+type ClientOptions struct {
+	// User overrides the user of connection
+	User string
+}
+
+type ClientOption func(*ClientOptions)
+
+// WithUser connects the host as the given user
+func WithUser(user string) ClientOption {
+	return func(options *ClientOptions) {
+		options.User = user
+	}
+}
+
+func newClientOptions(opts ...ClientOption) *ClientOptions {
+	options := &ClientOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// clientKey returns the key of client.IClient in cache, the clients of different users are separated
+func clientKey(name string, options *ClientOptions) string {
+	if options.User == "" {
+		return name
+	}
+	return options.User + "@" + name
+}
+
 // LoadSources builds the given source client.IClient, if the client.IClient already built, do nothing
 func (e *Executor) LoadSources(sources ...string) error {
 	var errs []error
 	for _, source := range sources {
-		_, err := e.GetClient(source)
+		cc, err := e.GetClient(source)
 		if err != nil {
 			errs = append(errs, err)
+			continue
 		}
+		e.ReleaseClient(cc)
 	}
 	return multierr.Combine(errs...)
 }
 
-func (e *Executor) GetClient(name string) (client.IClient, error) {
-	e.cmu.RLock()
-	cc, ok := e.clients[name]
-	e.cmu.RUnlock()
+// GetClient returns the cached client of host, the client is built if it doesn't exist
+// or it fails the health check. The caller releases the client by ReleaseClient.
+func (e *Executor) GetClient(name string, opts ...ClientOption) (client.IClient, error) {
+	options := newClientOptions(opts...)
+	key := clientKey(name, options)
 
-	if !ok {
-		var err error
-		cc, err = e.newClient(name)
-		if err != nil {
-			return nil, err
+	e.cmu.Lock()
+	cached, ok := e.clients[key]
+	if ok {
+		cached.refs += 1
+	}
+	e.cmu.Unlock()
+
+	if ok {
+		err := e.check(cached.cc)
+		if err == nil {
+			return cached.cc, nil
 		}
+		e.lg.Debug("reconnect bee connection",
+			zap.String("name", name),
+			zap.String("client", cached.cc.Name()),
+			zap.Error(err))
 		e.cmu.Lock()
-		e.clients[name] = cc
+		e.remove(cached)
 		e.cmu.Unlock()
+		e.ReleaseClient(cached.cc)
+	}
+
+	cc, err := e.dial(name, options)
+	if err != nil {
+		return nil, err
 	}
 
+	e.cmu.Lock()
+	defer e.cmu.Unlock()
+	if cached, ok = e.clients[key]; ok {
+		// the other caller has built one
+		cached.refs += 1
+		_ = cc.Close()
+		return cached.cc, nil
+	}
+	cached = &cachedClient{key: key, cc: cc, refs: 1, lastUsed: time.Now()}
+	e.clients[key] = cached
+	e.used[cc] = cached
 	return cc, nil
 }
 
-func (e *Executor) newClient(name string) (client.IClient, error) {
+// ReleaseClient releases the client returned by GetClient, the client keeps alive in cache
+// until it is idle for IdleTTL. The removed client is closed when all callers release it.
+func (e *Executor) ReleaseClient(cc client.IClient) {
+	e.cmu.Lock()
+	cached, ok := e.used[cc]
+	if !ok {
+		e.cmu.Unlock()
+		return
+	}
+	cached.refs -= 1
+	cached.lastUsed = time.Now()
+	closing := cached.removed && cached.refs <= 0
+	if closing {
+		delete(e.used, cc)
+	}
+	e.cmu.Unlock()
+
+	if closing {
+		if err := cc.Close(); err != nil {
+			e.lg.Warn("close bee connection", zap.String("client", cc.Name()), zap.Error(err))
+		}
+	}
+}
+
+// check returns error if the connection of client is broken
+func (e *Executor) check(cc client.IClient) error {
+	checker, ok := cc.(client.IHealthChecker)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	if timeout := e.opts.PingTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return checker.Ping(ctx)
+}
+
+// remove removes the client from cache, the client isn't returned by GetClient anymore
+func (e *Executor) remove(cached *cachedClient) {
+	if cached.removed {
+		return
+	}
+	cached.removed = true
+	if current, ok := e.clients[cached.key]; ok && current == cached {
+		delete(e.clients, cached.key)
+	}
+}
+
+// expireLoop closes the idle clients until Cleanup
+func (e *Executor) expireLoop(ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopping:
+			return
+		case <-ticker.C:
+		}
+		e.expire(ttl)
+	}
+}
+
+// expire closes the clients which aren't used for ttl
+func (e *Executor) expire(ttl time.Duration) {
+	expired := make([]client.IClient, 0)
+	e.cmu.Lock()
+	for _, cached := range e.clients {
+		if cached.refs <= 0 && time.Since(cached.lastUsed) > ttl {
+			e.remove(cached)
+			delete(e.used, cached.cc)
+			expired = append(expired, cached.cc)
+		}
+	}
+	e.cmu.Unlock()
+
+	for _, cc := range expired {
+		e.lg.Debug("close idle bee connection", zap.String("client", cc.Name()))
+		if err := cc.Close(); err != nil {
+			e.lg.Warn("close bee connection", zap.String("client", cc.Name()), zap.Error(err))
+		}
+	}
+}
+
+// User returns the user who connects to the host
+func (e *Executor) User(name string, opts ...ClientOption) string {
+	options := newClientOptions(opts...)
+	if options.User != "" {
+		return options.User
+	}
+
+	host, err := e.findHost(name)
+	if err != nil {
+		return ""
+	}
+	if user := host.Vars[vars.BeeUserVars]; user != "" {
+		return user
+	}
+	switch e.knownKind(host) {
+	case client.SSHClient:
+		return ssh.DefaultUser
+	case client.WinRMClient:
+		return winrm.DefaultWinRMUser
+	case client.LocalClient:
+		return local.CurrentUser()
+	}
+	return ""
+}
+
+// ConnectKind returns the kind of connection of host, see connectKind.
+// It is client.SmartClient if the smart connection isn't resolved yet.
+func (e *Executor) ConnectKind(name string) string {
+	host, err := e.findHost(name)
+	if err != nil {
+		return ""
+	}
+	return e.knownKind(host)
+}
+
+// knownKind returns the kind of connection of host without probing it
+func (e *Executor) knownKind(host *parser.Host) string {
+	kind := connectKind(host)
+	if kind == client.SmartClient {
+		e.kmu.Lock()
+		if resolved, ok := e.kinds[host.Name]; ok {
+			kind = resolved
+		}
+		e.kmu.Unlock()
+	}
+	return kind
+}
+
+// connectKind returns the kind of connection by bee_connect, defaults to ssh.
+// The localhost runs the commands by local connection if none of the connection vars is set.
+func connectKind(host *parser.Host) string {
+	if kind := host.Vars[vars.BeeConnectVars]; kind != "" {
+		return kind
+	}
+	if isLocalhost(host.Name) {
+		local := true
+		for _, key := range []string{vars.BeeHostVars, vars.BeePortVars, vars.BeeUserVars} {
+			if _, ok := host.Vars[key]; ok {
+				local = false
+			}
+		}
+		if local {
+			return client.LocalClient
+		}
+	}
+	return client.SSHClient
+}
+
+func isLocalhost(name string) bool {
+	switch name {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// findHost returns the host in inventory, the templates in the vars of it are rendered
+// before the host is dialed, see vars.RenderHostVars.
+func (e *Executor) findHost(name string) (*parser.Host, error) {
 	host, ok := e.inventory.FindHost(name)
 	if !ok {
 		return nil, ErrHostNotExists
 	}
+	return renderHost(host)
+}
+
+func renderHost(host *parser.Host) (*parser.Host, error) {
+	variables, err := vars.RenderHostVars(context.Background(), host.Name, host.Vars)
+	if err != nil {
+		return nil, err
+	}
+	out := *host
+	out.Vars = variables
+	return &out, nil
+}
+
+func (e *Executor) newClient(name string, options *ClientOptions) (client.IClient, error) {
+	host, err := e.findHost(name)
+	if err != nil {
+		return nil, err
+	}
 
-	kind := host.Vars[vars.BeeConnectVars]
-	if kind == "" {
-		kind = client.SSHClient
-	} else {
-		//TODO: todo when bee_connection=smart|local
+	kind, err := e.resolveKind(host)
+	if err != nil {
+		return nil, err
 	}
 
 	var cc client.IClient
-	var err error
 	switch kind {
+	case client.LocalClient:
+		cc, err = e.buildLocalClient(host)
 	case client.SSHClient:
-		cc, err = e.buildSSHClient(host)
+		cc, err = e.buildSSHClient(host, options)
 	case client.WinRMClient:
-		cc, err = e.buildWinRMClient(host)
+		cc, err = e.buildWinRMClient(host, options)
 	case client.GRPCClient:
 		cc, err = e.buildGRPCClient(host)
 	default:
 		return nil, ErrInvalidClient
 	}
+	if err != nil {
+		// the smart connection is probed again by the next client
+		e.forgetKind(name)
+	}
 
 	return cc, err
 }
 
-func (e *Executor) RemoveClient(name string) (bool, error) {
-	e.cmu.RLock()
-	conn, ok := e.clients[name]
-	e.cmu.RUnlock()
+// RemoveClient removes the client of host from cache, e.g. the connection is broken.
+// The client is closed when it isn't used, otherwise it is closed by ReleaseClient.
+func (e *Executor) RemoveClient(name string, opts ...ClientOption) (bool, error) {
+	key := clientKey(name, newClientOptions(opts...))
+	e.forgetKind(name)
+
+	e.cmu.Lock()
+	cached, ok := e.clients[key]
 	if !ok {
+		e.cmu.Unlock()
 		return false, nil
 	}
+	e.remove(cached)
+	closing := cached.refs <= 0
+	if closing {
+		delete(e.used, cached.cc)
+	}
+	e.cmu.Unlock()
 
-	e.cmu.Lock()
-	defer e.cmu.Unlock()
-	delete(e.clients, name)
-	err := conn.Close()
-	return ok, err
+	if !closing {
+		return true, nil
+	}
+	return true, cached.cc.Close()
 }
 
+// Cleanup closes all clients and stops closing the idle clients
 func (e *Executor) Cleanup() error {
-	var errs []error
+	e.stopOnce.Do(func() { close(e.stopping) })
 
 	e.cmu.Lock()
-	for _, cc := range e.clients {
+	used := e.used
+	e.clients = map[string]*cachedClient{}
+	e.used = map[client.IClient]*cachedClient{}
+	e.cmu.Unlock()
+
+	var errs []error
+	for cc := range used {
 		if err := cc.Close(); err != nil {
 			errs = append(errs, err)
 		}
 	}
-	e.cmu.Unlock()
 	return multierr.Combine(errs...)
 }
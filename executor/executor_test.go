@@ -0,0 +1,295 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client"
+	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/vars"
+)
+
+// fakeClient is the client.IClient which records the health check and close
+type fakeClient struct {
+	client.IClient
+
+	mu     sync.Mutex
+	broken bool
+	pings  int
+	closed bool
+}
+
+func (c *fakeClient) Name() string { return "fake" }
+
+func (c *fakeClient) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pings += 1
+	if c.broken {
+		return errors.New("broken pipe")
+	}
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeClient) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func newTestExecutor(t *testing.T, opts ...Option) (*Executor, *[]*fakeClient) {
+	e := NewExecutor(zap.NewNop(), nil, nil, opts...)
+	t.Cleanup(func() { _ = e.Cleanup() })
+
+	dialed := make([]*fakeClient, 0)
+	e.dial = func(name string, options *ClientOptions) (client.IClient, error) {
+		cc := &fakeClient{}
+		dialed = append(dialed, cc)
+		return cc, nil
+	}
+	return e, &dialed
+}
+
+func TestExecutor_ReuseClient(t *testing.T) {
+	e, dialed := newTestExecutor(t)
+
+	cc, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.ReleaseClient(cc)
+
+	reused, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.ReleaseClient(reused)
+	assert.Same(t, cc, reused)
+	assert.Len(t, *dialed, 1)
+	assert.Equal(t, 1, (*dialed)[0].pings)
+
+	// the clients of different users are separated
+	other, err := e.GetClient("host1", WithUser("admin"))
+	if assert.NoError(t, err) {
+		assert.NotSame(t, cc, other)
+		e.ReleaseClient(other)
+	}
+
+	assert.NoError(t, e.Cleanup())
+	for _, item := range *dialed {
+		assert.True(t, item.isClosed())
+	}
+}
+
+func TestExecutor_ReconnectBrokenClient(t *testing.T) {
+	e, dialed := newTestExecutor(t)
+
+	cc, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.ReleaseClient(cc)
+	(*dialed)[0].broken = true
+
+	reconnected, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotSame(t, cc, reconnected)
+	assert.Len(t, *dialed, 2)
+	assert.True(t, (*dialed)[0].isClosed())
+	assert.False(t, (*dialed)[1].isClosed())
+}
+
+func TestExecutor_RemoveUsedClient(t *testing.T) {
+	e, dialed := newTestExecutor(t)
+
+	cc, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ok, err := e.RemoveClient("host1")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	// the client is closed after the caller releases it
+	assert.False(t, (*dialed)[0].isClosed())
+	e.ReleaseClient(cc)
+	assert.True(t, (*dialed)[0].isClosed())
+
+	ok, _ = e.RemoveClient("host1")
+	assert.False(t, ok)
+}
+
+func TestExecutor_ExpireIdleClient(t *testing.T) {
+	ttl := time.Millisecond * 50
+	e, dialed := newTestExecutor(t, WithIdleTTL(ttl))
+
+	used, err := e.GetClient("host1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	idle, err := e.GetClient("host2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	e.ReleaseClient(idle)
+
+	time.Sleep(ttl * 2)
+	e.expire(ttl)
+	// the client in use isn't closed even if it is idle
+	assert.False(t, (*dialed)[0].isClosed())
+	assert.True(t, (*dialed)[1].isClosed())
+	e.ReleaseClient(used)
+
+	again, err := e.GetClient("host2")
+	if assert.NoError(t, err) {
+		assert.NotSame(t, idle, again)
+	}
+}
+
+func TestConnectKind(t *testing.T) {
+	cases := []struct {
+		name string
+		vars map[string]string
+		kind string
+	}{
+		{"localhost", nil, client.LocalClient},
+		{"127.0.0.1", map[string]string{}, client.LocalClient},
+		{"localhost", map[string]string{vars.BeePortVars: "2222"}, client.SSHClient},
+		{"localhost", map[string]string{vars.BeeConnectVars: client.GRPCClient}, client.GRPCClient},
+		{"web1", nil, client.SSHClient},
+		{"web1", map[string]string{vars.BeeConnectVars: client.LocalClient}, client.LocalClient},
+	}
+	for _, c := range cases {
+		host := &parser.Host{Name: c.name, Vars: c.vars}
+		assert.Equal(t, c.kind, connectKind(host), "%s %v", c.name, c.vars)
+	}
+}
+
+func TestExecutor_User(t *testing.T) {
+	dataloader := parser.NewDataLoader()
+	if err := dataloader.ParseString("web1 admin=root bee_user={{admin}}\nweb2 bee_user={{admin}}\n"); err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := inv.NewInventoryManager(dataloader, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExecutor(zap.NewNop(), inventory, nil)
+	t.Cleanup(func() { _ = e.Cleanup() })
+
+	assert.Equal(t, "root", e.User("web1"))
+	// bee_user refers to the undefined variable
+	assert.Equal(t, "", e.User("web2"))
+	_, err = e.newClient("web2", newClientOptions())
+	assert.ErrorContains(t, err, "undefined variable 'admin'")
+}
+
+// listenTCP serves the connections by sending banner, it returns the port of listener
+func listenTCP(t *testing.T, banner string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if banner != "" {
+				_, _ = conn.Write([]byte(banner))
+			}
+			go func() {
+				time.Sleep(time.Second)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestExecutor_ProbeSmartConnection(t *testing.T) {
+	e, _ := newTestExecutor(t, WithProbeTimeout(time.Millisecond*200))
+
+	port := listenTCP(t, "SSH-2.0-OpenSSH_9.0\r\n")
+	host := &parser.Host{Name: "web1", Vars: map[string]string{
+		vars.BeeConnectVars: client.SmartClient,
+		vars.BeeHostVars:    "127.0.0.1",
+		vars.BeePortVars:    port,
+	}}
+	kind, err := e.resolveKind(host)
+	assert.NoError(t, err)
+	assert.Equal(t, client.SSHClient, kind)
+	assert.Equal(t, client.SSHClient, e.knownKind(host))
+
+	// the port without ssh banner is winrm
+	port = listenTCP(t, "")
+	host = &parser.Host{Name: "win1", Vars: map[string]string{
+		vars.BeeConnectVars: client.SmartClient,
+		vars.BeeHostVars:    "127.0.0.1",
+		vars.BeePortVars:    port,
+	}}
+	kind, err = e.resolveKind(host)
+	assert.NoError(t, err)
+	assert.Equal(t, client.WinRMClient, kind)
+
+	e.forgetKind("win1")
+	assert.Equal(t, client.SmartClient, e.knownKind(host))
+}
+
+func TestExecutor_ProbeUnreachable(t *testing.T) {
+	e, _ := newTestExecutor(t, WithProbeTimeout(time.Millisecond*200))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_ = ln.Close()
+
+	host := &parser.Host{Name: "web1", Vars: map[string]string{
+		vars.BeeConnectVars: client.SmartClient,
+		vars.BeeHostVars:    "127.0.0.1",
+		vars.BeePortVars:    port,
+	}}
+	_, err = e.resolveKind(host)
+	assert.ErrorIs(t, err, client.ErrConnect)
+	assert.Equal(t, client.SmartClient, e.knownKind(host))
+}
@@ -0,0 +1,242 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package executor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client/ssh"
+	"github.com/olive-io/bee/parser"
+)
+
+// jumpHop is a bastion of bee_ssh_proxy_jump in format [user@]host[:port]
+type jumpHop struct {
+	User string
+	Host string
+	Port int
+}
+
+func (h *jumpHop) String() string {
+	text := h.Host
+	if h.Port > 0 {
+		text = net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+	}
+	if h.User != "" {
+		text = h.User + "@" + text
+	}
+	return text
+}
+
+// parseProxyJump parses the bastions separated by ',', e.g. "user@bastion:22,next"
+func parseProxyJump(text string) ([]*jumpHop, error) {
+	hops := make([]*jumpHop, 0)
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		hop := &jumpHop{Host: item}
+		if user, host, ok := strings.Cut(item, "@"); ok {
+			hop.User, hop.Host = user, host
+		}
+		if host, port, err := net.SplitHostPort(hop.Host); err == nil {
+			i, err := strconv.Atoi(port)
+			if err != nil || i <= 0 {
+				return nil, fmt.Errorf("invalid port of bastion '%s'", item)
+			}
+			hop.Host, hop.Port = host, i
+		}
+		if hop.Host == "" {
+			return nil, fmt.Errorf("invalid bastion '%s'", item)
+		}
+		hops = append(hops, hop)
+	}
+	if len(hops) == 0 {
+		return nil, fmt.Errorf("invalid proxy jump '%s'", text)
+	}
+	return hops, nil
+}
+
+// jumpKey returns the key of bastion in cache, the bastions are shared by the same chain of hops
+func jumpKey(hops []*jumpHop) string {
+	keys := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		keys = append(keys, hop.String())
+	}
+	return strings.Join(keys, ",")
+}
+
+// bastion is the ssh connection of the last hop of chain, it is closed when
+// it is released by all the hosts and the bastions behind it.
+type bastion struct {
+	key  string
+	cc   *ssh.Client
+	refs int
+	// parent is the bastion which cc goes through
+	parent *bastion
+}
+
+// jumpedClient is the ssh client of host which goes through the bastion,
+// it releases the bastion when it is closed.
+type jumpedClient struct {
+	*ssh.Client
+
+	release func()
+}
+
+func (c *jumpedClient) Close() error {
+	err := c.Client.Close()
+	c.release()
+	return err
+}
+
+// jumpLock serializes the connecting of the bastion of a chain, the callers of the
+// same chain wait for the first one and share its bastion.
+type jumpLock struct {
+	mu sync.Mutex
+	// refs is the number of callers which hold or wait for the lock
+	refs int
+}
+
+// lockJump locks the chain of key, the chains connect their bastions in parallel
+func (e *Executor) lockJump(key string) *jumpLock {
+	e.jmu.Lock()
+	l, ok := e.jlocks[key]
+	if !ok {
+		l = &jumpLock{}
+		e.jlocks[key] = l
+	}
+	l.refs += 1
+	e.jmu.Unlock()
+
+	l.mu.Lock()
+	return l
+}
+
+func (e *Executor) unlockJump(key string, l *jumpLock) {
+	l.mu.Unlock()
+
+	e.jmu.Lock()
+	defer e.jmu.Unlock()
+	l.refs -= 1
+	if l.refs <= 0 {
+		delete(e.jlocks, key)
+	}
+}
+
+// acquireJump returns the bastion of the last hop, the bastion is connected
+// through the former hops. The caller releases it by releaseJump.
+func (e *Executor) acquireJump(hops []*jumpHop) (*bastion, error) {
+	key := jumpKey(hops)
+	l := e.lockJump(key)
+	defer e.unlockJump(key, l)
+
+	e.jmu.Lock()
+	b, ok := e.jumps[key]
+	if ok {
+		b.refs += 1
+	}
+	e.jmu.Unlock()
+
+	if ok {
+		err := e.check(b.cc)
+		if err == nil {
+			return b, nil
+		}
+		// the broken bastion is closed when it is released by the hosts
+		e.lg.Debug("reconnect broken bastion", zap.String("bastion", key), zap.Error(err))
+		e.jmu.Lock()
+		if cached, ok := e.jumps[key]; ok && cached == b {
+			delete(e.jumps, key)
+		}
+		e.jmu.Unlock()
+		e.releaseJump(b)
+	}
+
+	var parent *bastion
+	var dialer ssh.Dialer
+	if len(hops) > 1 {
+		// the former hops are locked after the chain, they never wait for the longer one
+		var err error
+		parent, err = e.acquireJump(hops[:len(hops)-1])
+		if err != nil {
+			return nil, err
+		}
+		dialer = parent.cc
+	}
+
+	hop := hops[len(hops)-1]
+	var cc *ssh.Client
+	host, err := e.jumpHost(hop)
+	if err == nil {
+		cc, err = e.dialSSH(host, &ClientOptions{User: hop.User}, hop.Port, dialer)
+	}
+	if err != nil {
+		if parent != nil {
+			e.releaseJump(parent)
+		}
+		return nil, fmt.Errorf("connect bastion '%s': %w", hop, err)
+	}
+
+	b = &bastion{key: key, cc: cc, refs: 1, parent: parent}
+	e.jmu.Lock()
+	e.jumps[key] = b
+	e.jmu.Unlock()
+	return b, nil
+}
+
+// jumpHost returns the host of bastion, the bastion in inventory is connected
+// by its variables, e.g. bee_user, bee_ssh_passwd and bee_ssh_private_key.
+func (e *Executor) jumpHost(hop *jumpHop) (*parser.Host, error) {
+	if e.inventory != nil {
+		if host, ok := e.inventory.FindHost(hop.Host); ok {
+			return renderHost(host)
+		}
+	}
+	return &parser.Host{Name: hop.Host, Vars: map[string]string{}}, nil
+}
+
+// releaseJump releases the bastion, the bastion is closed when it isn't used,
+// then the bastions which it goes through are released.
+func (e *Executor) releaseJump(b *bastion) {
+	for b != nil {
+		e.jmu.Lock()
+		b.refs -= 1
+		closing := b.refs <= 0
+		if cached, ok := e.jumps[b.key]; closing && ok && cached == b {
+			delete(e.jumps, b.key)
+		}
+		e.jmu.Unlock()
+		if !closing {
+			return
+		}
+
+		e.lg.Debug("close bastion", zap.String("bastion", b.key))
+		if err := b.cc.Close(); err != nil {
+			e.lg.Debug("close bastion", zap.String("bastion", b.key), zap.Error(err))
+		}
+		b = b.parent
+	}
+}
@@ -0,0 +1,320 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package executor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	cssh "golang.org/x/crypto/ssh"
+
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/secret"
+	testdb "github.com/olive-io/bee/test/db"
+	"github.com/olive-io/bee/vars"
+)
+
+// testSSHServer accepts the user "tester" and forwards the direct-tcpip channels like a bastion
+type testSSHServer struct {
+	host   string
+	port   int
+	opened atomic.Int32
+	active atomic.Int32
+}
+
+func startSSHServer(t *testing.T, passwd string) *testSSHServer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := cssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &cssh.ServerConfig{
+		PasswordCallback: func(conn cssh.ConnMetadata, password []byte) (*cssh.Permissions, error) {
+			if conn.User() == "tester" && string(password) == passwd {
+				return nil, nil
+			}
+			return nil, errors.New("permission denied")
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &testSSHServer{host: addr.IP.String(), port: addr.Port}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, cfg)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, cfg *cssh.ServerConfig) {
+	sconn, chans, reqs, err := cssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	s.opened.Add(1)
+	s.active.Add(1)
+	defer s.active.Add(-1)
+	go cssh.DiscardRequests(reqs)
+
+	for nch := range chans {
+		if nch.ChannelType() != "direct-tcpip" {
+			_ = nch.Reject(cssh.UnknownChannelType, "unsupported channel")
+			continue
+		}
+		var payload struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err = cssh.Unmarshal(nch.ExtraData(), &payload); err != nil {
+			_ = nch.Reject(cssh.Prohibited, err.Error())
+			continue
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			_ = nch.Reject(cssh.ConnectionFailed, err.Error())
+			continue
+		}
+		ch, creqs, err := nch.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+		go cssh.DiscardRequests(creqs)
+		go func() {
+			_, _ = io.Copy(ch, target)
+			_ = ch.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(target, ch)
+			_ = target.Close()
+		}()
+	}
+	_ = sconn.Close()
+}
+
+func (s *testSSHServer) hop() string {
+	return "tester@" + net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+func newJumpExecutor(t *testing.T, bastions ...*testSSHServer) *Executor {
+	db, err := testdb.NewDB(zap.NewNop(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	passwords := secret.NewPasswordManager(zap.NewNop(), db)
+	for _, b := range bastions {
+		// the bastions are connected by the passwords of "tester@<host>"
+		if err = passwords.SetPassword("tester@"+b.host, "bastion", secret.WithNamespace("ssh")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	e := NewExecutor(zap.NewNop(), nil, passwords, WithKnownHosts(knownHosts))
+	t.Cleanup(func() { _ = e.Cleanup() })
+	return e
+}
+
+func newJumpHost(name string, target *testSSHServer, hops ...*testSSHServer) *parser.Host {
+	jumps := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		jumps = append(jumps, hop.hop())
+	}
+	return &parser.Host{Name: name, Vars: map[string]string{
+		vars.BeeHostVars:          target.host,
+		vars.BeePortVars:          strconv.Itoa(target.port),
+		vars.BeeUserVars:          "tester",
+		vars.BeeSSHPasswdVars:     "target",
+		vars.BeeSSHPrivateKeyVars: filepath.Join(os.TempDir(), "bee-missing-key"),
+		vars.BeeSSHProxyJumpVars:  strings.Join(jumps, ","),
+	}}
+}
+
+func TestParseProxyJump(t *testing.T) {
+	hops, err := parseProxyJump("admin@bastion:2222, next")
+	if assert.NoError(t, err) && assert.Len(t, hops, 2) {
+		assert.Equal(t, &jumpHop{User: "admin", Host: "bastion", Port: 2222}, hops[0])
+		assert.Equal(t, &jumpHop{Host: "next"}, hops[1])
+		assert.Equal(t, "admin@bastion:2222,next", jumpKey(hops))
+	}
+
+	for _, text := range []string{"", " , ", "admin@", "bastion:ssh"} {
+		_, err = parseProxyJump(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestExecutor_ProxyJump(t *testing.T) {
+	bastion := startSSHServer(t, "bastion")
+	target := startSSHServer(t, "target")
+	e := newJumpExecutor(t, bastion)
+
+	c1, err := e.buildSSHClient(newJumpHost("web1", target, bastion), &ClientOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	c2, err := e.buildSSHClient(newJumpHost("web2", target, bastion), &ClientOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, e.check(c1))
+
+	// the hosts share the connection of bastion
+	assert.Equal(t, int32(1), bastion.opened.Load())
+	assert.Equal(t, int32(2), target.opened.Load())
+	assert.Len(t, e.jumps, 1)
+
+	assert.NoError(t, c1.Close())
+	assert.Len(t, e.jumps, 1)
+	assert.NoError(t, c2.Close())
+	assert.Len(t, e.jumps, 0)
+	assert.Eventually(t, func() bool { return bastion.active.Load() == 0 }, time.Second*3, time.Millisecond*10)
+}
+
+func TestExecutor_ProxyJumpChain(t *testing.T) {
+	first := startSSHServer(t, "bastion")
+	second := startSSHServer(t, "bastion")
+	target := startSSHServer(t, "target")
+	e := newJumpExecutor(t, first, second)
+
+	cc, err := e.buildSSHClient(newJumpHost("web1", target, first, second), &ClientOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, e.check(cc))
+	assert.Equal(t, int32(1), first.opened.Load())
+	assert.Equal(t, int32(1), second.opened.Load())
+	assert.Len(t, e.jumps, 2)
+
+	assert.NoError(t, cc.Close())
+	assert.Len(t, e.jumps, 0)
+	assert.Eventually(t, func() bool {
+		return first.active.Load() == 0 && second.active.Load() == 0
+	}, time.Second*3, time.Millisecond*10)
+}
+
+func TestExecutor_ProxyJumpUnreachable(t *testing.T) {
+	bastion := startSSHServer(t, "bastion")
+	e := newJumpExecutor(t)
+
+	target := startSSHServer(t, "target")
+	_, err := e.buildSSHClient(newJumpHost("web1", target, bastion), &ClientOptions{})
+	// the password of bastion is missing
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connect bastion")
+	assert.Len(t, e.jumps, 0)
+}
+
+func TestExecutor_ProxyJumpConcurrent(t *testing.T) {
+	bastion := startSSHServer(t, "bastion")
+	target := startSSHServer(t, "target")
+
+	// the stalled bastion accepts the connections but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	stalled := &testSSHServer{host: addr.IP.String(), port: addr.Port}
+	e := newJumpExecutor(t, bastion, stalled)
+
+	stalledDone := make(chan error, 1)
+	go func() {
+		_, err := e.buildSSHClient(newJumpHost("db1", target, stalled), &ClientOptions{})
+		stalledDone <- err
+	}()
+	var conn net.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(time.Second * 3):
+		t.Fatal("the stalled bastion isn't connected")
+	}
+
+	// the chain of the other bastion isn't blocked by the stalled one
+	var wg sync.WaitGroup
+	clients := make([]client.IClient, 8)
+	errs := make([]error, len(clients))
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], errs[i] = e.buildSSHClient(newJumpHost("web"+strconv.Itoa(i), target, bastion), &ClientOptions{})
+		}(i)
+	}
+	wg.Wait()
+	for i := range clients {
+		if assert.NoError(t, errs[i]) {
+			assert.NoError(t, clients[i].Close())
+		}
+	}
+	// the callers of the same chain share the bastion
+	assert.Equal(t, int32(1), bastion.opened.Load())
+
+	_ = conn.Close()
+	_ = ln.Close()
+	select {
+	case err = <-stalledDone:
+		assert.Error(t, err)
+	case <-time.After(time.Second * 3):
+		t.Fatal("the stalled bastion isn't closed")
+	}
+	e.jmu.Lock()
+	defer e.jmu.Unlock()
+	assert.Len(t, e.jumps, 0)
+	assert.Len(t, e.jlocks, 0)
+}
@@ -0,0 +1,164 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/executor/client/grpc"
+	"github.com/olive-io/bee/executor/client/ssh"
+	"github.com/olive-io/bee/executor/client/winrm"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/vars"
+)
+
+// DefaultProbeTimeout is the timeout of probing each port of smart connection
+const DefaultProbeTimeout = time.Second * 3
+
+// sshBanner is the prefix of identification string sent by ssh server
+const sshBanner = "SSH-"
+
+// resolveKind returns the kind of connection of host, the smart connection is
+// resolved by probing the host once, the result is cached until RemoveClient.
+func (e *Executor) resolveKind(host *parser.Host) (string, error) {
+	kind := connectKind(host)
+	if kind != client.SmartClient {
+		return kind, nil
+	}
+	if host.Vars[vars.BeeSSHProxyJumpVars] != "" {
+		// the host behind bastions is only reachable by ssh
+		return client.SSHClient, nil
+	}
+
+	e.kmu.Lock()
+	resolved, ok := e.kinds[host.Name]
+	e.kmu.Unlock()
+	if ok {
+		return resolved, nil
+	}
+
+	resolved, err := e.probe(host)
+	if err != nil {
+		return "", err
+	}
+	e.lg.Debug("resolve smart connection",
+		zap.String("name", host.Name),
+		zap.String("client", resolved))
+
+	e.kmu.Lock()
+	e.kinds[host.Name] = resolved
+	e.kmu.Unlock()
+	return resolved, nil
+}
+
+// forgetKind drops the resolved smart connection of host, it is probed again by the next client
+func (e *Executor) forgetKind(name string) {
+	e.kmu.Lock()
+	delete(e.kinds, name)
+	e.kmu.Unlock()
+}
+
+// probe finds the connection of host in the order of ssh, winrm and grpc.
+// The ssh server is recognized by its banner, the others by the reachable ports.
+// bee_port overrides the default ports of all the connections.
+func (e *Executor) probe(host *parser.Host) (string, error) {
+	ch := host.Name
+	if val, ok := host.Vars[vars.BeeHostVars]; ok {
+		ch = val
+	}
+	ch, _, _ = strings.Cut(ch, ":")
+
+	port := 0
+	if val, ok := host.Vars[vars.BeePortVars]; ok {
+		if i, _ := strconv.ParseInt(val, 10, 64); i > 0 {
+			port = int(i)
+		}
+	}
+	portOf := func(defaultPort int) string {
+		if port > 0 {
+			return strconv.Itoa(port)
+		}
+		return strconv.Itoa(defaultPort)
+	}
+
+	timeout := e.opts.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	candidates := []struct {
+		kind   string
+		addr   string
+		banner string
+	}{
+		{kind: client.SSHClient, addr: net.JoinHostPort(ch, portOf(ssh.DefaultPort)), banner: sshBanner},
+		{kind: client.WinRMClient, addr: net.JoinHostPort(ch, portOf(winrm.DefaultWinRMPort))},
+		{kind: client.GRPCClient, addr: net.JoinHostPort(ch, portOf(grpc.DefaultGRPCPort))},
+	}
+
+	tried := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		err := probeAddr(candidate.addr, candidate.banner, timeout)
+		if err == nil {
+			return candidate.kind, nil
+		}
+		e.lg.Debug("probe smart connection",
+			zap.String("name", host.Name),
+			zap.String("client", candidate.kind),
+			zap.String("addr", candidate.addr),
+			zap.Error(err))
+		tried = append(tried, candidate.kind+"://"+candidate.addr)
+	}
+
+	return "", errors.Wrapf(client.ErrConnect, "no reachable connection of host '%s' (%s)",
+		host.Name, strings.Join(tried, ", "))
+}
+
+// probeAddr checks if addr is reachable, the server must send the banner first if it isn't empty
+func probeAddr(addr, banner string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if banner == "" {
+		return nil
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return err
+	}
+	if !strings.HasPrefix(line, banner) {
+		return fmt.Errorf("unexpected banner %q", strings.TrimSpace(line))
+	}
+	return nil
+}
@@ -17,7 +17,11 @@
 
 package executor
 
-import "reflect"
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
 
 type Zone int
 
@@ -34,6 +38,7 @@ const (
 	Skipped
 	Rescued
 	Ignored
+	Failures
 )
 
 var zoneS = map[Zone]string{
@@ -44,9 +49,12 @@ var zoneS = map[Zone]string{
 	Skipped:   "skipped",
 	Rescued:   "rescued",
 	Ignored:   "ignored",
+	Failures:  "failures",
 }
 
+// AggregateStats counts the task results of hosts by Zone, it is safe for concurrent use
 type AggregateStats struct {
+	mu         sync.RWMutex
 	aggregates map[Zone]map[string]int64
 	custom     map[string]map[string]any
 }
@@ -62,7 +70,15 @@ func NewStats() *AggregateStats {
 	return as
 }
 
+// Increment increases the count of host in the zone, marks the host as processed
 func (as *AggregateStats) Increment(what Zone, host string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.aggregates[Processed][host] = 1
+	if what == Processed {
+		return
+	}
 	stats, ok := as.aggregates[what]
 	if !ok {
 		as.aggregates[what] = map[string]int64{}
@@ -76,6 +92,9 @@ func (as *AggregateStats) Increment(what Zone, host string) {
 }
 
 func (as *AggregateStats) Decrement(what Zone, host string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
 	stats, ok := as.aggregates[what]
 	if !ok {
 		as.aggregates[what] = map[string]int64{}
@@ -85,20 +104,70 @@ func (as *AggregateStats) Decrement(what Zone, host string) {
 	if !ok {
 		stats[host] = 0
 	}
-	if stats[host] > 1 {
+	if stats[host] > 0 {
 		stats[host] -= 1
 	}
 }
 
+// Get returns the count of host in the zone
+func (as *AggregateStats) Get(what Zone, host string) int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.aggregates[what][host]
+}
+
+// Hosts returns the processed hosts in order
+func (as *AggregateStats) Hosts() []string {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	hosts := make([]string, 0, len(as.aggregates[Processed]))
+	for host := range as.aggregates[Processed] {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Summarize returns the counts of host, the key is the name of Zone
 func (as *AggregateStats) Summarize(host string) map[string]int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
 	stat := map[string]int64{}
 	for zone, stats := range as.aggregates {
-		stats[zone.String()] = stats[host]
+		stat[zone.String()] = stats[host]
 	}
 	return stat
 }
 
+// Merge adds the counts of other to the stats, e.g. sums the stats of several plays
+func (as *AggregateStats) Merge(other *AggregateStats) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for zone, stats := range other.aggregates {
+		target, ok := as.aggregates[zone]
+		if !ok {
+			target = map[string]int64{}
+			as.aggregates[zone] = target
+		}
+		for host, count := range stats {
+			if zone == Processed {
+				target[host] = 1
+				continue
+			}
+			target[host] += count
+		}
+	}
+}
+
 func (as *AggregateStats) GetCustomStats(host, which string) (any, bool) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
 	customs, ok := as.custom[host]
 	if !ok {
 		return nil, false
@@ -108,6 +177,12 @@ func (as *AggregateStats) GetCustomStats(host, which string) (any, bool) {
 }
 
 func (as *AggregateStats) SetCustomStats(which string, what any, host string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.setCustomStats(which, what, host)
+}
+
+func (as *AggregateStats) setCustomStats(which string, what any, host string) {
 	if host == "" {
 		host = "_run"
 	}
@@ -120,17 +195,20 @@ func (as *AggregateStats) SetCustomStats(which string, what any, host string) {
 }
 
 func (as *AggregateStats) UpdateCustomStats(which string, what any, host string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
 	if host == "" {
 		host = "_run"
 	}
 	customs, ok := as.custom[host]
 	if !ok {
-		as.SetCustomStats(which, what, host)
+		as.setCustomStats(which, what, host)
 		return
 	}
 	value, ok := customs[which]
 	if !ok {
-		as.SetCustomStats(which, what, host)
+		as.setCustomStats(which, what, host)
 		return
 	}
 
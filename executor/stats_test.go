@@ -53,3 +53,33 @@ func TestAggregateStats(t *testing.T) {
 	value, _ = as.GetCustomStats("host1", "s")
 	assert.Equal(t, "hello world", value)
 }
+
+func TestAggregateStats_Summarize(t *testing.T) {
+	as := NewStats()
+	as.Increment(Ok, "host2")
+	as.Increment(Ok, "host1")
+	as.Increment(Changed, "host1")
+	as.Increment(Ok, "host1")
+	as.Increment(Dark, "host3")
+	as.Decrement(Ok, "host2")
+	as.Decrement(Ok, "host2")
+
+	assert.Equal(t, []string{"host1", "host2", "host3"}, as.Hosts())
+
+	summary := as.Summarize("host1")
+	assert.Equal(t, int64(1), summary["processed"])
+	assert.Equal(t, int64(2), summary["ok"])
+	assert.Equal(t, int64(1), summary["changed"])
+	assert.Equal(t, int64(0), summary["failures"])
+	assert.Equal(t, int64(0), as.Get(Ok, "host2"))
+	assert.Equal(t, int64(1), as.Get(Dark, "host3"))
+
+	other := NewStats()
+	other.Increment(Ok, "host1")
+	other.Increment(Failures, "host4")
+	as.Merge(other)
+	assert.Equal(t, []string{"host1", "host2", "host3", "host4"}, as.Hosts())
+	assert.Equal(t, int64(3), as.Get(Ok, "host1"))
+	assert.Equal(t, int64(1), as.Get(Processed, "host1"))
+	assert.Equal(t, int64(1), as.Get(Failures, "host4"))
+}
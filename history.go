@@ -0,0 +1,377 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	json "github.com/json-iterator/go"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/history"
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// History returns the records of Play and Execute
+func (rt *Runtime) History() *history.Store {
+	return rt.history
+}
+
+// OpenHistory opens the history in the root directory of bee without Runtime,
+// the returned function closes the db.
+func OpenHistory(lg *zap.Logger, dir string) (*history.Store, func() error, error) {
+	db, err := openDB(lg, filepath.Join(dir, "db"))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "open embded db")
+	}
+	return history.NewStore(lg, db), db.Close, nil
+}
+
+// saveRun stores the run in the history, the failure is logged only
+func (rt *Runtime) saveRun(run *history.Run) {
+	if err := rt.history.Save(run); err != nil {
+		rt.Logger().Error("save run history",
+			zap.String("run", run.Id),
+			zap.Error(err))
+	}
+}
+
+// recordPlay stores the play in the history, the results are collected by recorder
+func (rt *Runtime) recordPlay(pr *process.Process, report *RunReport, recorder *historyCallBack, err error, options *RunOptions) {
+	run := &history.Run{
+		Id:        report.RunId,
+		Kind:      history.KindPlay,
+		ProcessId: pr.Id,
+		Process:   pr.Name,
+		StartAt:   report.StartAt,
+		EndAt:     report.EndAt,
+		Results:   recorder.Results(),
+		Stats:     summarize(report.Stats),
+	}
+	if options.resume != nil {
+		run.Resumed = options.resume.RunId
+	}
+	if err != nil {
+		run.Error = redactText(options.Filter, err.Error())
+	}
+	rt.saveRun(run)
+}
+
+// recordExecute stores the execute in the history, the secrets are masked by the filters of options
+func (rt *Runtime) recordExecute(host, shell string, startAt time.Time, data []byte, err error, opts ...RunOption) {
+	options := newRunOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	action, _, _ := strings.Cut(shell, " ")
+	command := redactText(options.Filter, shell)
+	if options.NoLog {
+		command = action
+	}
+
+	as := bexecutor.NewStats()
+	result := &stats.TaskResult{Host: host, Task: action, NoLog: options.NoLog}
+	if err != nil {
+		result.ErrMsg = redactText(options.Filter, err.Error())
+		if errors.Is(err, client.ErrConnect) {
+			as.Increment(bexecutor.Dark, host)
+		} else {
+			as.Increment(bexecutor.Failures, host)
+		}
+	} else {
+		stdout := map[string]any{}
+		if e1 := json.Unmarshal(data, &stdout); e1 != nil {
+			stdout = map[string]any{"stdout": string(data)}
+		}
+		for _, sf := range filter.SecretFilters(options.Filter) {
+			stdout = sf.OnPostTaskStdout(action, stdout)
+		}
+		result.Stdout = stdout
+		result.Changed = stats.IsChanged(stdout)
+		as.Increment(bexecutor.Ok, host)
+		if result.Changed {
+			as.Increment(bexecutor.Changed, host)
+		}
+	}
+
+	rt.saveRun(&history.Run{
+		Kind:    history.KindExecute,
+		Command: command,
+		Hosts:   []string{host},
+		StartAt: startAt,
+		EndAt:   time.Now(),
+		Results: []*stats.TaskResult{result.Censored()},
+		Stats:   summarize(as),
+	})
+}
+
+// redactText masks the secrets in text by the secret filters, see filter.ISecretFilter
+func redactText(ft filter.IFilter, text string) string {
+	for _, sf := range filter.SecretFilters(ft) {
+		text = sf.Redact(text)
+	}
+	return text
+}
+
+// redactVars returns the copy of vars which the secrets are masked by the secret filters,
+// the values of secret names are masked entirely, see filter.ISecretFilter AddSecretVars
+func redactVars(ft filter.IFilter, vars map[string]any) map[string]any {
+	sfs := filter.SecretFilters(ft)
+	if len(sfs) == 0 || vars == nil {
+		return vars
+	}
+	for _, sf := range sfs {
+		sf.AddSecretVars(vars)
+	}
+
+	var redact func(value any) any
+	redact = func(value any) any {
+		switch tt := value.(type) {
+		case nil:
+			return nil
+		case map[string]any:
+			out := make(map[string]any, len(tt))
+			for key, item := range tt {
+				out[key] = redact(item)
+			}
+			return out
+		case []any:
+			out := make([]any, len(tt))
+			for i, item := range tt {
+				out[i] = redact(item)
+			}
+			return out
+		case string:
+			return redactText(ft, tt)
+		default:
+			// the secrets which aren't string, e.g. the numeric passwords
+			text := fmt.Sprintf("%v", tt)
+			if redactText(ft, text) != text {
+				return filter.RedactedValue
+			}
+			return tt
+		}
+	}
+	return redact(vars).(map[string]any)
+}
+
+// redactProcess returns the yaml of process which the secrets of vars are masked by the
+// secret filters, e.g. the vars of process and tasks, see redactVars.
+func redactProcess(ft filter.IFilter, pr *process.Process) (string, error) {
+	data, err := yaml.Marshal(pr)
+	if err != nil {
+		return "", err
+	}
+	sfs := filter.SecretFilters(ft)
+	if len(sfs) == 0 {
+		return string(data), nil
+	}
+
+	out := map[string]any{}
+	if err = yaml.Unmarshal(data, &out); err != nil {
+		return "", err
+	}
+	// the vars of tasks are in the lists, the secret filters don't walk them
+	var walk func(value any)
+	walk = func(value any) {
+		switch tt := value.(type) {
+		case map[string]any:
+			for _, sf := range sfs {
+				sf.AddSecretVars(tt)
+			}
+			for _, item := range tt {
+				walk(item)
+			}
+		case []any:
+			for _, item := range tt {
+				walk(item)
+			}
+		}
+	}
+	walk(out)
+
+	data, err = yaml.Marshal(redactVars(ft, out))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// maskedProcessVars returns the names of vars of process and tasks which contain the
+// masked secrets, in order, see redactProcess.
+func maskedProcessVars(text string) ([]string, error) {
+	out := map[string]any{}
+	if err := yaml.Unmarshal([]byte(text), &out); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	var walk func(value any)
+	walk = func(value any) {
+		switch tt := value.(type) {
+		case map[string]any:
+			for key, item := range tt {
+				if vars, ok := item.(map[string]any); ok && key == "vars" {
+					names = append(names, maskedVars(vars)...)
+					continue
+				}
+				walk(item)
+			}
+		case []any:
+			for _, item := range tt {
+				walk(item)
+			}
+		}
+	}
+	walk(out)
+
+	return lo.Uniq[string](names), nil
+}
+
+// maskedVars returns the names of vars which contain the masked secrets, in order
+func maskedVars(vars map[string]any) []string {
+	var masked func(value any) bool
+	masked = func(value any) bool {
+		switch tt := value.(type) {
+		case string:
+			return strings.Contains(tt, filter.RedactedValue)
+		case map[string]any:
+			for _, item := range tt {
+				if masked(item) {
+					return true
+				}
+			}
+		case []any:
+			for _, item := range tt {
+				if masked(item) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	names := make([]string, 0)
+	for key, value := range vars {
+		if masked(value) {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summarize returns the summary of hosts, key is the host
+func summarize(as *bexecutor.AggregateStats) map[string]map[string]int64 {
+	summary := map[string]map[string]int64{}
+	for _, host := range as.Hosts() {
+		summary[host] = as.Summarize(host)
+	}
+	return summary
+}
+
+// historyCallBack collects the task results of play in order
+type historyCallBack struct {
+	callback.BaseCallBack
+
+	mu      sync.Mutex
+	results []*stats.TaskResult
+}
+
+func (h *historyCallBack) add(result *stats.TaskResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.results = append(h.results, result)
+}
+
+// Results returns the collected results
+func (h *historyCallBack) Results() []*stats.TaskResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*stats.TaskResult{}, h.results...)
+}
+
+func (h *historyCallBack) RunnerOnUnreachable(result *stats.TaskResult) {
+	h.add(result)
+}
+
+func (h *historyCallBack) RunnerOnOk(result *stats.TaskResult) {
+	h.add(result)
+}
+
+func (h *historyCallBack) RunnerOkFailed(result *stats.TaskResult) {
+	h.add(result)
+}
+
+func (h *historyCallBack) RunnerOnSkipped(result *stats.TaskResult) {
+	h.add(result)
+}
+
+// checkpointer records the progress of play in the checkpoint of run, the results of
+// the resumed run are looked up from prev.
+type checkpointer struct {
+	rt *Runtime
+
+	mu sync.Mutex
+	cp *history.Checkpoint
+	// prev is the checkpoint of the resumed run, nil if the play isn't resumed
+	prev *history.Checkpoint
+}
+
+func newCheckpointer(rt *Runtime, cp, prev *history.Checkpoint) *checkpointer {
+	return &checkpointer{rt: rt, cp: cp, prev: prev}
+}
+
+// succeeded returns the result of task on the host if it succeeded in the resumed run
+func (c *checkpointer) succeeded(id, host string) (*stats.TaskResult, bool) {
+	if c.prev == nil {
+		return nil, false
+	}
+	return c.prev.Succeeded(id, host)
+}
+
+// record stores the outcomes of task on hosts
+func (c *checkpointer) record(id string, results []*stats.TaskResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cp.Record(id, results)
+	c.save()
+}
+
+// save stores the checkpoint, the failure is logged only
+func (c *checkpointer) save() {
+	if err := c.rt.history.SaveCheckpoint(c.cp); err != nil {
+		c.rt.Logger().Error("save checkpoint",
+			zap.String("run", c.cp.RunId),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,201 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	json "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/stats"
+)
+
+const (
+	checkpointPrefix = "_bee/checkpoint/"
+)
+
+// Checkpoint is the progress of play, the interrupted play resumes from it
+type Checkpoint struct {
+	// RunId is the id of run which the checkpoint belongs to
+	RunId string `json:"run_id"`
+	// Resumed is the id of run which is resumed by the run
+	Resumed string `json:"resumed,omitempty"`
+	// Name is the name of process
+	Name string `json:"name,omitempty"`
+	// Process is the yaml of process, the ids of tasks are set
+	Process string `json:"process"`
+
+	// ExtraVars, Limit, Check and Diff are the options of play, the secrets
+	// of ExtraVars are masked
+	ExtraVars map[string]any `json:"extra_vars,omitempty"`
+	Limit     []string       `json:"limit,omitempty"`
+	Check     bool           `json:"check,omitempty"`
+	Diff      bool           `json:"diff,omitempty"`
+
+	// Completed are the ids of tasks which succeeded on all hosts
+	Completed []string `json:"completed,omitempty"`
+	// Tasks are the outcomes of tasks, key is the id of task then the host
+	Tasks map[string]map[string]*stats.TaskResult `json:"tasks,omitempty"`
+
+	UpdateAt time.Time `json:"update_at"`
+}
+
+func NewCheckpoint(runId string) *Checkpoint {
+	return &Checkpoint{
+		RunId:     runId,
+		Completed: []string{},
+		Tasks:     map[string]map[string]*stats.TaskResult{},
+	}
+}
+
+// Resume returns the checkpoint of run which resumes this one, the outcomes are kept
+func (c *Checkpoint) Resume(runId string) *Checkpoint {
+	out := NewCheckpoint(runId)
+	out.Resumed = c.RunId
+	out.Name = c.Name
+	out.Process = c.Process
+	out.ExtraVars = c.ExtraVars
+	out.Limit = c.Limit
+	out.Check = c.Check
+	out.Diff = c.Diff
+	out.Completed = append(out.Completed, c.Completed...)
+	for id, hosts := range c.Tasks {
+		out.Tasks[id] = map[string]*stats.TaskResult{}
+		for host, result := range hosts {
+			out.Tasks[id][host] = result
+		}
+	}
+	return out
+}
+
+// Record stores the outcomes of task on hosts, the task is completed when
+// it succeeded on all recorded hosts. The outputs of no_log results are censored.
+func (c *Checkpoint) Record(id string, results []*stats.TaskResult) {
+	if c.Tasks == nil {
+		c.Tasks = map[string]map[string]*stats.TaskResult{}
+	}
+	hosts, ok := c.Tasks[id]
+	if !ok {
+		hosts = map[string]*stats.TaskResult{}
+		c.Tasks[id] = hosts
+	}
+	for _, result := range results {
+		hosts[result.Host] = result.Censored()
+	}
+
+	completed := make([]string, 0, len(c.Completed)+1)
+	for _, item := range c.Completed {
+		if item != id {
+			completed = append(completed, item)
+		}
+	}
+	c.Completed = completed
+	for _, result := range hosts {
+		if result.ErrMsg != "" {
+			return
+		}
+	}
+	c.Completed = append(c.Completed, id)
+}
+
+// IsCompleted reports whether the task succeeded on all hosts
+func (c *Checkpoint) IsCompleted(id string) bool {
+	for _, item := range c.Completed {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Succeeded returns the result of task on the host if it succeeded. The no_log results
+// are censored in the checkpoint, they are never succeeded so that the task runs again.
+func (c *Checkpoint) Succeeded(id, host string) (*stats.TaskResult, bool) {
+	result, ok := c.Tasks[id][host]
+	if !ok || result.ErrMsg != "" || result.NoLog {
+		return nil, false
+	}
+	return result, true
+}
+
+// SaveCheckpoint stores the checkpoint, the checkpoint of the same run is replaced
+func (s *Store) SaveCheckpoint(cp *Checkpoint) error {
+	cp.UpdateAt = time.Now()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	err = s.db.Set(checkpointKey(cp.RunId), data, &pebble.WriteOptions{Sync: true})
+	return parseErr(err)
+}
+
+// GetCheckpoint returns the checkpoint of run
+func (s *Store) GetCheckpoint(runId string) (*Checkpoint, error) {
+	value, closer, err := s.db.Get(checkpointKey(runId))
+	if err != nil {
+		return nil, parseErr(err)
+	}
+	defer closer.Close()
+
+	cp := NewCheckpoint(runId)
+	if err = json.Unmarshal(value, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// ListCheckpoints returns the checkpoints from newest to oldest, the broken records are skipped
+func (s *Store) ListCheckpoints() ([]*Checkpoint, error) {
+	cps := make([]*Checkpoint, 0)
+	err := s.scanCheckpoints(func(cp *Checkpoint) bool {
+		cps = append(cps, cp)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cps, nil
+}
+
+// DeleteCheckpoint deletes the checkpoint of run
+func (s *Store) DeleteCheckpoint(runId string) error {
+	err := s.db.Delete(checkpointKey(runId), &pebble.WriteOptions{Sync: true})
+	return parseErr(err)
+}
+
+// scanCheckpoints calls fn with the checkpoints from newest to oldest until it returns false,
+// the broken records are skipped.
+func (s *Store) scanCheckpoints(fn func(cp *Checkpoint) bool) error {
+	return s.iterate(checkpointPrefix, func(key, value []byte) bool {
+		cp := NewCheckpoint(strings.TrimPrefix(string(key), checkpointPrefix))
+		if err := json.Unmarshal(value, cp); err != nil {
+			s.lg.Warn("decode checkpoint",
+				zap.String("key", string(key)),
+				zap.Error(err))
+			return true
+		}
+		return fn(cp)
+	})
+}
+
+func checkpointKey(runId string) []byte {
+	return []byte(checkpointPrefix + runId)
+}
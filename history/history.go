@@ -0,0 +1,352 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+	json "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/stats"
+)
+
+const (
+	defaultPrefix = "_bee/history/"
+
+	// KindPlay is the run of process, see Runtime.Play
+	KindPlay = "play"
+	// KindExecute is the run of module command, see Runtime.Execute
+	KindExecute = "execute"
+)
+
+var (
+	ErrNotFound    = errors.New("run not found")
+	ErrDBOperation = errors.New("failed to operate db")
+)
+
+// Run is the record of a play or execute
+type Run struct {
+	// Id is ordered by the start time, see NewRunId
+	Id        string `json:"id"`
+	Kind      string `json:"kind"`
+	ProcessId string `json:"process_id,omitempty"`
+	Process   string `json:"process,omitempty"`
+	// Command is the command line of module of execute
+	Command string   `json:"command,omitempty"`
+	Hosts   []string `json:"hosts,omitempty"`
+	// Resumed is the id of run which is resumed by the play, see Checkpoint
+	Resumed string `json:"resumed,omitempty"`
+
+	StartAt time.Time `json:"start_at"`
+	// EndAt is zero until the run ends, see Unfinished
+	EndAt time.Time `json:"end_at"`
+
+	// Results are the task results of hosts in order
+	Results []*stats.TaskResult `json:"results,omitempty"`
+	// Error is the error of run, the errors of hosts are in Results
+	Error string `json:"error,omitempty"`
+	// Stats is the summary of task results of hosts, see executor.AggregateStats Summarize
+	Stats map[string]map[string]int64 `json:"stats,omitempty"`
+}
+
+// Name returns the name of process or the command of module
+func (r *Run) Name() string {
+	if r.Kind == KindExecute {
+		return r.Command
+	}
+	return r.Process
+}
+
+// Failed returns true if the run or any task result fails
+func (r *Run) Failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, result := range r.Results {
+		if result.ErrMsg != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Unfinished reports whether the run hasn't ended, it is running or was interrupted
+func (r *Run) Unfinished() bool {
+	return r.EndAt.IsZero()
+}
+
+// HasHost reports whether the run contains the host
+func (r *Run) HasHost(host string) bool {
+	for _, item := range r.Hosts {
+		if item == host {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRunId returns the unique id of run which starts at the time, the ids are
+// ordered by the start time.
+func NewRunId(startAt time.Time) string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%016x%s", startAt.UnixNano(), hex.EncodeToString(suffix))
+}
+
+type ListOptions struct {
+	// Host selects the runs on the host
+	Host string
+	// Process selects the runs of process by the name or id
+	Process string
+	// Since and Until select the runs started in the range
+	Since time.Time
+	Until time.Time
+	// Limit is the max number of runs, 0 means no limit
+	Limit int
+}
+
+type ListOption func(*ListOptions)
+
+func WithHost(host string) ListOption {
+	return func(options *ListOptions) {
+		options.Host = host
+	}
+}
+
+func WithProcess(process string) ListOption {
+	return func(options *ListOptions) {
+		options.Process = process
+	}
+}
+
+func WithSince(since time.Time) ListOption {
+	return func(options *ListOptions) {
+		options.Since = since
+	}
+}
+
+func WithUntil(until time.Time) ListOption {
+	return func(options *ListOptions) {
+		options.Until = until
+	}
+}
+
+func WithLimit(limit int) ListOption {
+	return func(options *ListOptions) {
+		options.Limit = limit
+	}
+}
+
+func (o *ListOptions) match(run *Run) bool {
+	if o.Host != "" && !run.HasHost(o.Host) {
+		return false
+	}
+	if o.Process != "" && run.Process != o.Process && run.ProcessId != o.Process {
+		return false
+	}
+	if !o.Since.IsZero() && run.StartAt.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && run.StartAt.After(o.Until) {
+		return false
+	}
+	return true
+}
+
+// Store keeps the runs in the embedded db
+type Store struct {
+	lg *zap.Logger
+	db *pebble.DB
+}
+
+func NewStore(lg *zap.Logger, db *pebble.DB) *Store {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+
+	store := &Store{
+		lg: lg,
+		db: db,
+	}
+	return store
+}
+
+// Save stores the run, the run with the same id is replaced
+func (s *Store) Save(run *Run) error {
+	if run.Id == "" {
+		run.Id = NewRunId(run.StartAt)
+	}
+	if len(run.Hosts) == 0 {
+		run.Hosts = resultHosts(run.Results)
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	err = s.db.Set(runKey(run.Id), data, &pebble.WriteOptions{Sync: true})
+	return parseErr(err)
+}
+
+// Get returns the run by the id
+func (s *Store) Get(id string) (*Run, error) {
+	value, closer, err := s.db.Get(runKey(id))
+	if err != nil {
+		return nil, parseErr(err)
+	}
+	defer closer.Close()
+
+	run := &Run{}
+	if err = json.Unmarshal(value, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// List returns the runs match the options, from newest to oldest
+func (s *Store) List(opts ...ListOption) ([]*Run, error) {
+	options := &ListOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	runs := make([]*Run, 0)
+	err := s.scan(func(run *Run) bool {
+		if options.match(run) {
+			runs = append(runs, run)
+		}
+		return options.Limit <= 0 || len(runs) < options.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Prune deletes the runs started before the time and the checkpoints last updated
+// before it, returns the number of deleted runs
+func (s *Store) Prune(before time.Time) (int, error) {
+	keys := make([][]byte, 0)
+	runs := 0
+	err := s.scan(func(run *Run) bool {
+		if run.StartAt.Before(before) {
+			keys = append(keys, runKey(run.Id))
+			runs += 1
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = s.scanCheckpoints(func(cp *Checkpoint) bool {
+		if cp.UpdateAt.Before(before) {
+			keys = append(keys, checkpointKey(cp.RunId))
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, key := range keys {
+		if err = batch.Delete(key, nil); err != nil {
+			return 0, parseErr(err)
+		}
+	}
+	if err = batch.Commit(&pebble.WriteOptions{Sync: true}); err != nil {
+		return 0, parseErr(err)
+	}
+	return runs, nil
+}
+
+// scan calls fn with the runs from newest to oldest until it returns false,
+// the broken records are skipped.
+func (s *Store) scan(fn func(run *Run) bool) error {
+	return s.iterate(defaultPrefix, func(key, value []byte) bool {
+		run := &Run{}
+		if err := json.Unmarshal(value, run); err != nil {
+			s.lg.Warn("decode run history",
+				zap.String("key", string(key)),
+				zap.Error(err))
+			return true
+		}
+		return fn(run)
+	})
+}
+
+// iterate calls fn with the records of the prefix from the last key to the first one
+// until it returns false
+func (s *Store) iterate(prefix string, fn func(key, value []byte) bool) error {
+	// the keys of records are in [lower, upper)
+	lower := []byte(prefix)
+	upper := []byte(prefix)
+	upper[len(upper)-1] += 1
+	iter, err := s.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return parseErr(err)
+	}
+	defer iter.Close()
+
+	for valid := iter.Last(); valid; valid = iter.Prev() {
+		if !fn(iter.Key(), iter.Value()) {
+			break
+		}
+	}
+	return parseErr(iter.Error())
+}
+
+func runKey(id string) []byte {
+	return []byte(defaultPrefix + id)
+}
+
+// resultHosts returns the hosts of results in order
+func resultHosts(results []*stats.TaskResult) []string {
+	seen := map[string]struct{}{}
+	hosts := make([]string, 0)
+	for _, result := range results {
+		if _, ok := seen[result.Host]; ok {
+			continue
+		}
+		seen[result.Host] = struct{}{}
+		hosts = append(hosts, result.Host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func parseErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pebble.ErrNotFound) {
+		return ErrNotFound
+	}
+	return errors.Join(err, ErrDBOperation)
+}
@@ -0,0 +1,194 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package history_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/history"
+	"github.com/olive-io/bee/stats"
+	testdb "github.com/olive-io/bee/test/db"
+)
+
+func newStore(t *testing.T) *history.Store {
+	db, err := testdb.NewDB(zap.NewNop(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return history.NewStore(zap.NewNop(), db)
+}
+
+func TestStore(t *testing.T) {
+	store := newStore(t)
+
+	day := time.Date(2024, 5, 7, 10, 0, 0, 0, time.UTC)
+	runs := []*history.Run{
+		{
+			Kind:    history.KindPlay,
+			Process: "site",
+			StartAt: day,
+			EndAt:   day.Add(time.Minute),
+			Results: []*stats.TaskResult{{Host: "web1", Task: "ping"}, {Host: "web2", Task: "ping"}},
+		},
+		{
+			Kind:    history.KindExecute,
+			Command: "ping",
+			StartAt: day.Add(time.Hour),
+			EndAt:   day.Add(time.Hour),
+			Results: []*stats.TaskResult{{Host: "db1", Task: "ping", ErrMsg: "connect refused"}},
+		},
+		{
+			Kind:    history.KindPlay,
+			Process: "site",
+			StartAt: day.Add(time.Hour * 24),
+			EndAt:   day.Add(time.Hour * 24),
+			Results: []*stats.TaskResult{{Host: "web1", Task: "ping"}},
+		},
+	}
+	for _, run := range runs {
+		if !assert.NoError(t, store.Save(run)) {
+			return
+		}
+	}
+	assert.Equal(t, []string{"web1", "web2"}, runs[0].Hosts)
+
+	got, err := store.Get(runs[1].Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ping", got.Name())
+		assert.True(t, got.Failed())
+	}
+	_, err = store.Get("unknown")
+	assert.ErrorIs(t, err, history.ErrNotFound)
+
+	ids := func(runs []*history.Run) []string {
+		out := make([]string, 0, len(runs))
+		for _, run := range runs {
+			out = append(out, run.Id)
+		}
+		return out
+	}
+
+	listed, err := store.List()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{runs[2].Id, runs[1].Id, runs[0].Id}, ids(listed))
+	}
+	listed, err = store.List(history.WithHost("web1"), history.WithUntil(day.Add(time.Hour*2)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{runs[0].Id}, ids(listed))
+	}
+	listed, err = store.List(history.WithProcess("site"), history.WithLimit(1))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{runs[2].Id}, ids(listed))
+	}
+
+	pruned, err := store.Prune(day.Add(time.Hour * 2))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, pruned)
+	}
+	listed, err = store.List()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{runs[2].Id}, ids(listed))
+	}
+}
+
+func TestStore_Checkpoint(t *testing.T) {
+	store := newStore(t)
+
+	cp := history.NewCheckpoint(history.NewRunId(time.Now()))
+	cp.Process = "name: site"
+	cp.Record("t1", []*stats.TaskResult{{Host: "web1", TaskId: "t1"}, {Host: "web2", TaskId: "t1"}})
+	cp.Record("t2", []*stats.TaskResult{{Host: "web1", TaskId: "t2"}, {Host: "web2", TaskId: "t2", ErrMsg: "failed"}})
+	assert.True(t, cp.IsCompleted("t1"))
+	assert.False(t, cp.IsCompleted("t2"))
+	if !assert.NoError(t, store.SaveCheckpoint(cp)) {
+		return
+	}
+
+	got, err := store.GetCheckpoint(cp.RunId)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "name: site", got.Process)
+	assert.Equal(t, []string{"t1"}, got.Completed)
+	_, ok := got.Succeeded("t2", "web1")
+	assert.True(t, ok)
+	_, ok = got.Succeeded("t2", "web2")
+	assert.False(t, ok)
+	_, ok = got.Succeeded("t3", "web1")
+	assert.False(t, ok)
+
+	// the failed host succeeds in the resumed run
+	resumed := got.Resume(history.NewRunId(time.Now()))
+	assert.Equal(t, cp.RunId, resumed.Resumed)
+	resumed.Record("t2", []*stats.TaskResult{{Host: "web2", TaskId: "t2"}})
+	assert.True(t, resumed.IsCompleted("t2"))
+	assert.False(t, got.IsCompleted("t2"))
+
+	// the no_log results are censored and run again
+	cp.Record("t3", []*stats.TaskResult{{Host: "web1", TaskId: "t3", NoLog: true, Stdout: map[string]any{"token": "s3cret"}}})
+	result := cp.Tasks["t3"]["web1"]
+	assert.Equal(t, stats.NoLogMessage, result.Stdout["censored"])
+	assert.NotContains(t, result.Stdout, "token")
+	assert.True(t, cp.IsCompleted("t3"))
+	_, ok = cp.Succeeded("t3", "web1")
+	assert.False(t, ok)
+
+	assert.NoError(t, store.DeleteCheckpoint(cp.RunId))
+	_, err = store.GetCheckpoint(cp.RunId)
+	assert.ErrorIs(t, err, history.ErrNotFound)
+}
+
+func TestStore_PruneCheckpoints(t *testing.T) {
+	store := newStore(t)
+
+	now := time.Now()
+	first := history.NewCheckpoint(history.NewRunId(now.Add(-time.Hour)))
+	second := history.NewCheckpoint(history.NewRunId(now))
+	for _, cp := range []*history.Checkpoint{first, second} {
+		if !assert.NoError(t, store.SaveCheckpoint(cp)) {
+			return
+		}
+	}
+	// only the run of the second checkpoint is recorded
+	assert.NoError(t, store.Save(&history.Run{Id: second.RunId, Kind: history.KindPlay, StartAt: now}))
+
+	cps, err := store.ListCheckpoints()
+	if assert.NoError(t, err) && assert.Len(t, cps, 2) {
+		assert.Equal(t, second.RunId, cps[0].RunId)
+		assert.Equal(t, first.RunId, cps[1].RunId)
+	}
+
+	pruned, err := store.Prune(now.Add(-time.Minute))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, pruned)
+	}
+	// the checkpoints are pruned by the time of last update
+	pruned, err = store.Prune(time.Now().Add(time.Minute))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, pruned)
+	}
+	cps, err = store.ListCheckpoints()
+	if assert.NoError(t, err) {
+		assert.Empty(t, cps)
+	}
+}
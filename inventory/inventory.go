@@ -18,6 +18,7 @@
 package inventory
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/samber/lo"
@@ -98,6 +99,47 @@ func (im *Manager) AddSources(sources ...string) error {
 	return nil
 }
 
+// ResolveHosts expands the given patterns into the names of matched hosts.
+// A pattern matches hosts by name and, through groups, all hosts of the
+// matched groups. The resolved hosts are registered as sources and returned
+// in lexical order.
+func (im *Manager) ResolveHosts(patterns ...string) ([]string, error) {
+	matched := make(map[string]*parser.Host)
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		hosts, err := im.loader.MatchHosts(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for name, host := range hosts {
+			matched[name] = host
+		}
+
+		groups, err := im.loader.MatchGroups(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			for name, host := range group.Hosts {
+				matched[name] = host
+			}
+		}
+	}
+
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := im.AddSources(names...); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
 func (im *Manager) MatchedGroups() (map[string]*parser.Group, error) {
 	im.RLock()
 	defer im.RUnlock()
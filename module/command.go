@@ -34,6 +34,9 @@ import (
 
 const (
 	PrefixFlag = "__flag_"
+
+	// RedactedValue replaces the values of secret params in logs
+	RedactedValue = "********"
 )
 
 type CommandErr struct {
@@ -77,6 +80,8 @@ type RunContext struct {
 	Cmd       *Command
 	Conn      client.IClient
 	Variables *StableMap
+	// Redact masks the secrets in the logs of command
+	Redact func(text string) string
 }
 
 type RunE func(ctx *RunContext, options ...client.ExecOption) ([]byte, error)
@@ -95,6 +100,7 @@ type Command struct {
 	Mutable  bool           `json:"mutable,omitempty" yaml:"mutable,omitempty"`
 	Hide     bool           `json:"hide,omitempty" yaml:"hide,omitempty"`
 	Root     string         `json:"root,omitempty" yaml:"root,omitempty"`
+	Check    bool           `json:"check,omitempty" yaml:"check,omitempty"`
 	cobra    *cobra.Command `yaml:"-"`
 
 	PreRun  RunE `json:"-" yaml:"-"`
@@ -158,13 +164,27 @@ var DefaultRunCommand RunE = func(ctx *RunContext, opts ...client.ExecOption) ([
 		opt(eOpts)
 	}
 
+	secrets := map[string]struct{}{}
+	for _, param := range command.Params {
+		if param.Secret {
+			secrets[param.Name] = struct{}{}
+		}
+	}
+
 	args := make([]string, 0)
+	// logArgs are the args in logs, the values of secret params are masked
+	logArgs := make([]string, 0)
 	command.Flags().VisitAll(func(flag *pflag.Flag) {
 		value := ctx.Variables.GetDefault(PrefixFlag+flag.Name, flag.Value.String())
 		arg := "--" + flag.Name + "=" + value
 		args = append(args, arg)
+		if _, ok := secrets[flag.Name]; ok {
+			arg = "--" + flag.Name + "=" + RedactedValue
+		}
+		logArgs = append(logArgs, arg)
 	})
 	args = append(args, eOpts.Args...)
+	logArgs = append(logArgs, eOpts.Args...)
 
 	options := make([]client.ExecOption, 0)
 	ext, ok := KnownExt(path.Ext(command.Script))
@@ -210,9 +230,23 @@ var DefaultRunCommand RunE = func(ctx *RunContext, opts ...client.ExecOption) ([
 	for key, value := range eOpts.Environments {
 		options = append(options, client.ExecWithEnv(key, value))
 	}
+	if eOpts.Check {
+		// the modules get check mode by bee.check_mode()
+		options = append(options, client.ExecWithEnv(client.EnvCheckMode, "true"))
+	}
+	if eOpts.Diff {
+		// the modules get diff mode by bee.diff_mode()
+		options = append(options, client.ExecWithEnv(client.EnvDiffMode, "true"))
+	}
+	if eOpts.Become != nil {
+		options = append(options, client.ExecWithBecome(eOpts.Become))
+	}
 
 	shell := fmt.Sprintf("%s -import %s %s %s",
-		repl, resolve, script, strings.Join(args, " "))
+		repl, resolve, script, strings.Join(logArgs, " "))
+	if ctx.Redact != nil {
+		shell = ctx.Redact(shell)
+	}
 	start := time.Now()
 	cmd, err := conn.Execute(ctx, repl, options...)
 	if err != nil {
@@ -30,6 +30,7 @@ type Schema struct {
 	Desc    string       `json:"desc,omitempty" yaml:"desc,omitempty"`
 	Default string       `json:"default,omitempty" yaml:"default,omitempty"`
 	Example string       `json:"example,omitempty" yaml:"example,omitempty"`
+	Secret  bool         `json:"secret,omitempty" yaml:"secret,omitempty"`
 	Value   *SchemaValue `json:"-" yaml:"-"`
 }
 
@@ -22,10 +22,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/olive-io/bpmn/tracing"
 	"go.uber.org/zap"
 
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client/ssh"
+	"github.com/olive-io/bee/history"
 	"github.com/olive-io/bee/plugins/callback"
 	"github.com/olive-io/bee/plugins/filter"
 )
@@ -40,8 +44,12 @@ type Options struct {
 	dir      string
 	parallel int
 	check    bool
+	idleTTL  time.Duration
 	logger   *zap.Logger
 	caller   Callable
+
+	hostKeyChecking string
+	knownHosts      []string
 }
 
 func newOptions() *Options {
@@ -49,7 +57,10 @@ func newOptions() *Options {
 	options := Options{
 		dir:      filepath.Join(home, ".bee"),
 		parallel: DefaultParallel,
+		idleTTL:  bexecutor.DefaultIdleTTL,
 		logger:   zap.NewExample(),
+
+		hostKeyChecking: ssh.DefaultHostKeyChecking,
 	}
 	return &options
 }
@@ -74,6 +85,30 @@ func SetCheck(check bool) Option {
 	}
 }
 
+// SetIdleTTL closes the connections of hosts which are idle for ttl, the connections
+// are reused by the runs until then. They are kept until Stop if ttl is 0.
+func SetIdleTTL(ttl time.Duration) Option {
+	return func(opt *Options) {
+		opt.idleTTL = ttl
+	}
+}
+
+// SetHostKeyChecking sets the mode of checking the ssh host keys, it is one of
+// strict, accept-new and off. The hosts override it by bee_ssh_host_key_checking.
+func SetHostKeyChecking(mode string) Option {
+	return func(opt *Options) {
+		opt.hostKeyChecking = mode
+	}
+}
+
+// SetKnownHosts sets the known_hosts files of ssh host keys, the new keys are recorded
+// in the first one. They default to <dir>/known_hosts and ~/.ssh/known_hosts.
+func SetKnownHosts(files ...string) Option {
+	return func(opt *Options) {
+		opt.knownHosts = files
+	}
+}
+
 func SetLogger(lg *zap.Logger) Option {
 	return func(opt *Options) {
 		opt.logger = lg
@@ -92,7 +127,33 @@ type RunOptions struct {
 	Tracer    chan tracing.ITrace
 	Metadata  map[string]any
 	ExtraArgs map[string]string
-	sync      bool
+	ExtraVars map[string]any
+	Limit     []string
+	// Check runs the modules in check mode, the modules report what they would change
+	// without changing it. It is enabled for all runs by SetCheck.
+	Check bool
+	// Diff asks the modules to return the unified diff of changed content in "diff" field
+	Diff bool
+	// Stats counts the results of hosts, e.g. the skipped hosts
+	Stats *bexecutor.AggregateStats
+	// NoLog hides the command line of module from the logs
+	NoLog bool
+	// RemoteUser overrides the user of connection
+	RemoteUser string
+	// Become runs the modules as BecomeUser by privilege escalation
+	Become     bool
+	BecomeUser string
+	// RunId is the id of play in the history, see history.NewRunId
+	RunId string
+	sync  bool
+	// checkpoint records the progress of play, see Runtime.Resume
+	checkpoint *checkpointer
+	// resume is the checkpoint of run which the play resumes
+	resume *history.Checkpoint
+	// registry keeps the registered outputs of tasks across the batches of play
+	registry *registry
+	// batch runs the process on a batch of serial, the failed hosts don't stop the others
+	batch bool
 }
 
 func newRunOptions() *RunOptions {
@@ -111,15 +172,23 @@ func WithRunSync(b bool) RunOption {
 	}
 }
 
-func WithRunCallback(cb callback.ICallBack) RunOption {
+// WithRunCallback appends the callbacks, the events are sent to all of them in order
+func WithRunCallback(cbs ...callback.ICallBack) RunOption {
+	return func(opt *RunOptions) {
+		opt.Callback = callback.Compose(append([]callback.ICallBack{opt.Callback}, cbs...)...)
+	}
+}
+
+// WithRunFilter appends the filters, the output of a filter is the input of next one
+func WithRunFilter(fts ...filter.IFilter) RunOption {
 	return func(opt *RunOptions) {
-		opt.Callback = cb
+		opt.Filter = filter.Compose(append([]filter.IFilter{opt.Filter}, fts...)...)
 	}
 }
 
-func WithRunFilter(f filter.IFilter) RunOption {
+func WithRunStats(as *bexecutor.AggregateStats) RunOption {
 	return func(opt *RunOptions) {
-		opt.Filter = f
+		opt.Stats = as
 	}
 }
 
@@ -150,3 +219,96 @@ func WithArgs(args map[string]string) RunOption {
 		}
 	}
 }
+
+// WithRunExtraVars sets the variables which take precedence over all other variables
+func WithRunExtraVars(vars map[string]any) RunOption {
+	return func(opt *RunOptions) {
+		if opt.ExtraVars == nil {
+			opt.ExtraVars = map[string]any{}
+		}
+		for key, value := range vars {
+			opt.ExtraVars[key] = value
+		}
+	}
+}
+
+// WithRunLimit limits the process to run on the given hosts
+func WithRunLimit(hosts ...string) RunOption {
+	return func(opt *RunOptions) {
+		opt.Limit = append(opt.Limit, hosts...)
+	}
+}
+
+// WithRunNoLog hides the command line of module from the logs
+func WithRunNoLog(noLog bool) RunOption {
+	return func(opt *RunOptions) {
+		opt.NoLog = noLog
+	}
+}
+
+// WithRunCheck runs the modules in check mode
+func WithRunCheck(check bool) RunOption {
+	return func(opt *RunOptions) {
+		opt.Check = check
+	}
+}
+
+// WithRunDiff asks the modules to return the diff of changed content, see stats.RenderDiff
+func WithRunDiff(diff bool) RunOption {
+	return func(opt *RunOptions) {
+		opt.Diff = diff
+	}
+}
+
+// WithRunId sets the id of play in the history, it is generated when the play starts by default.
+// The caller knows the id before the play ends by it, e.g. to resume the interrupted play.
+func WithRunId(id string) RunOption {
+	return func(opt *RunOptions) {
+		opt.RunId = id
+	}
+}
+
+// withRunBatch runs the process on the batch of hosts, replaces the limit
+func withRunBatch(hosts []string) RunOption {
+	return func(opt *RunOptions) {
+		opt.Limit = hosts
+		opt.batch = true
+	}
+}
+
+// withRunCheckpoint records the progress of play by the checkpointer
+func withRunCheckpoint(ckp *checkpointer) RunOption {
+	return func(opt *RunOptions) {
+		opt.checkpoint = ckp
+	}
+}
+
+// withRunResume skips the tasks and hosts which succeeded in the checkpoint
+func withRunResume(cp *history.Checkpoint) RunOption {
+	return func(opt *RunOptions) {
+		opt.resume = cp
+	}
+}
+
+// withRunRegistry shares the registered outputs of tasks between the runners of play
+func withRunRegistry(rg *registry) RunOption {
+	return func(opt *RunOptions) {
+		opt.registry = rg
+	}
+}
+
+// WithRunUser connects the hosts as the given user
+func WithRunUser(user string) RunOption {
+	return func(opt *RunOptions) {
+		opt.RemoteUser = user
+	}
+}
+
+// WithRunBecome runs the modules as the given user by privilege escalation,
+// the user defaults to bee_become_user of host or root.
+func WithRunBecome(become bool, user string) RunOption {
+	return func(opt *RunOptions) {
+		opt.Become = become
+		opt.BecomeUser = user
+	}
+}
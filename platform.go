@@ -0,0 +1,237 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+	json "github.com/json-iterator/go"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/module"
+	"github.com/olive-io/bee/vars"
+)
+
+const (
+	// DefaultDetectTimeout is the timeout of detecting the platform of host
+	DefaultDetectTimeout = time.Second * 30
+
+	platformPrefix = "_bee/platform/"
+)
+
+// hostPlatform is the os and architecture of host, see detectPlatform
+type hostPlatform struct {
+	Platform string    `json:"platform"`
+	Arch     string    `json:"arch"`
+	DetectAt time.Time `json:"detectAt"`
+}
+
+func platformKey(host string) []byte {
+	return []byte(platformPrefix + host)
+}
+
+// applyStableMap returns the variables of module command on host. It fails if the
+// platform of host isn't set in inventory and fails to be detected, nothing is cached.
+func (rt *Runtime) applyStableMap(ctx context.Context, host string, conn client.IClient) (*module.StableMap, error) {
+	sm := module.NewVariables()
+	home := rt.variables.MustGetHostDefaultValue(host, vars.BeeHome, "/tmp/bee")
+	sm.Set(vars.BeeHome, home)
+
+	// bee_platform and bee_arch in inventory take precedence over the detected ones
+	goos := rt.variables.MustGetHostDefaultValue(host, vars.BeePlatformVars, "")
+	arch := rt.variables.MustGetHostDefaultValue(host, vars.BeeArchVars, "")
+	if goos == "" || arch == "" {
+		detected, err := rt.hostPlatform(ctx, host, conn)
+		if err != nil {
+			return nil, errors.Wrapf(err, "detect platform of host '%s', set %s and %s to skip it",
+				host, vars.BeePlatformVars, vars.BeeArchVars)
+		}
+		if goos == "" {
+			goos = detected.Platform
+		}
+		if arch == "" {
+			arch = detected.Arch
+		}
+	}
+	sm.Set(vars.BeePlatformVars, goos)
+	sm.Set(vars.BeeArchVars, arch)
+	return sm, nil
+}
+
+// hostPlatform returns the platform of host, it is detected on the first connection
+// and cached in db, see ResetPlatform.
+func (rt *Runtime) hostPlatform(ctx context.Context, host string, conn client.IClient) (*hostPlatform, error) {
+	if conn.Name() == client.LocalClient {
+		// the local connection runs on the controller itself
+		return &hostPlatform{Platform: runtime.GOOS, Arch: runtime.GOARCH}, nil
+	}
+
+	rt.pmu.Lock()
+	cached, ok := rt.platforms[host]
+	rt.pmu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	value, closer, err := rt.db.Get(platformKey(host))
+	if err == nil {
+		cached = &hostPlatform{}
+		err = json.Unmarshal(value, cached)
+		_ = closer.Close()
+		if err == nil {
+			rt.cachePlatform(host, cached)
+			return cached, nil
+		}
+	}
+	if err != nil && !errors.Is(err, pebble.ErrNotFound) {
+		rt.Logger().Warn("load platform", zap.String("host", host), zap.Error(err))
+	}
+
+	dctx, cancel := context.WithTimeout(ctx, DefaultDetectTimeout)
+	defer cancel()
+	detected, err := detectPlatform(dctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	detected.DetectAt = time.Now()
+	rt.Logger().Debug("detect platform",
+		zap.String("host", host),
+		zap.String("platform", detected.Platform),
+		zap.String("arch", detected.Arch))
+
+	data, err := json.Marshal(detected)
+	if err == nil {
+		err = rt.db.Set(platformKey(host), data, &pebble.WriteOptions{Sync: true})
+	}
+	if err != nil {
+		rt.Logger().Warn("save platform", zap.String("host", host), zap.Error(err))
+	}
+	rt.cachePlatform(host, detected)
+	return detected, nil
+}
+
+func (rt *Runtime) cachePlatform(host string, platform *hostPlatform) {
+	rt.pmu.Lock()
+	rt.platforms[host] = platform
+	rt.pmu.Unlock()
+}
+
+// ResetPlatform drops the detected platforms of hosts, e.g. the host is reinstalled.
+// The platforms are detected again by the next connections.
+func (rt *Runtime) ResetPlatform(hosts ...string) error {
+	rt.pmu.Lock()
+	for _, host := range hosts {
+		delete(rt.platforms, host)
+	}
+	rt.pmu.Unlock()
+
+	for _, host := range hosts {
+		if err := rt.db.Delete(platformKey(host), &pebble.WriteOptions{Sync: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detectPlatform detects the platform of remote host by `uname -sm`, the windows
+// hosts are detected by the environment variable PROCESSOR_ARCHITECTURE.
+func detectPlatform(ctx context.Context, conn client.IClient) (*hostPlatform, error) {
+	if conn.Name() == client.WinRMClient {
+		// winrm runs the command by powershell
+		out, err := execOutput(ctx, conn, "$env:PROCESSOR_ARCHITECTURE")
+		if err != nil {
+			return nil, err
+		}
+		return parseWindowsArch(out)
+	}
+
+	out, err := execOutput(ctx, conn, "uname", "-sm")
+	if err == nil {
+		return parseUname(out)
+	}
+	// uname isn't found on windows, e.g. OpenSSH server of windows
+	out, e1 := execOutput(ctx, conn, "powershell.exe", "-NoProfile", "-Command", "$env:PROCESSOR_ARCHITECTURE")
+	if e1 != nil {
+		return nil, errors.Wrapf(err, "powershell: %v", e1)
+	}
+	return parseWindowsArch(out)
+}
+
+func execOutput(ctx context.Context, conn client.IClient, name string, args ...string) (string, error) {
+	cmd, err := conn.Execute(ctx, name, client.ExecWithArgs(args...))
+	if err != nil {
+		return "", err
+	}
+	data, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", &module.CommandErr{Err: err, Stderr: data}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseUname parses the output of `uname -sm`, e.g. "Linux x86_64"
+func parseUname(out string) (*hostPlatform, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected output of uname: %q", out)
+	}
+
+	goos := strings.ToLower(fields[0])
+	switch {
+	case goos == "sunos":
+		goos = "solaris"
+	case strings.HasPrefix(goos, "mingw"), strings.HasPrefix(goos, "msys"), strings.HasPrefix(goos, "cygwin"):
+		goos = "windows"
+	}
+
+	return &hostPlatform{Platform: goos, Arch: normalizeArch(fields[1])}, nil
+}
+
+// parseWindowsArch parses the value of PROCESSOR_ARCHITECTURE, e.g. "AMD64"
+func parseWindowsArch(out string) (*hostPlatform, error) {
+	if out == "" || strings.ContainsAny(out, " \t\n") {
+		return nil, fmt.Errorf("unexpected PROCESSOR_ARCHITECTURE: %q", out)
+	}
+	return &hostPlatform{Platform: "windows", Arch: normalizeArch(out)}, nil
+}
+
+// normalizeArch converts the machine name to GOARCH
+func normalizeArch(machine string) string {
+	machine = strings.ToLower(machine)
+	switch machine {
+	case "x86_64", "amd64", "x64":
+		return "amd64"
+	case "aarch64", "arm64", "aarch64_be":
+		return "arm64"
+	case "i386", "i486", "i586", "i686", "x86":
+		return "386"
+	case "ppc64le", "ppc64", "s390x", "riscv64", "mips64", "mips64le":
+		return machine
+	}
+	if strings.HasPrefix(machine, "armv") || machine == "arm" {
+		return "arm"
+	}
+	return machine
+}
@@ -0,0 +1,183 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/vars"
+)
+
+// unameClient is the client.IClient of a linux host, it records the executed commands
+type unameClient struct {
+	client.IClient
+
+	out   string
+	execs []string
+}
+
+func (c *unameClient) Name() string { return client.SSHClient }
+
+func (c *unameClient) Execute(ctx context.Context, shell string, opts ...client.ExecOption) (client.ICmd, error) {
+	c.execs = append(c.execs, shell)
+	if shell != "uname" {
+		return nil, errors.New("unknown command")
+	}
+	return &outputCmd{out: c.out}, nil
+}
+
+type outputCmd struct {
+	client.ICmd
+
+	out string
+}
+
+func (c *outputCmd) CombinedOutput() ([]byte, error) {
+	return []byte(c.out + "\n"), nil
+}
+
+func TestParseUname(t *testing.T) {
+	cases := []struct {
+		out      string
+		platform string
+		arch     string
+	}{
+		{"Linux x86_64", "linux", "amd64"},
+		{"Linux aarch64", "linux", "arm64"},
+		{"Darwin arm64", "darwin", "arm64"},
+		{"Linux armv7l", "linux", "arm"},
+		{"FreeBSD amd64", "freebsd", "amd64"},
+		{"MINGW64_NT-10.0 x86_64", "windows", "amd64"},
+	}
+	for _, c := range cases {
+		platform, err := parseUname(c.out)
+		if assert.NoError(t, err, c.out) {
+			assert.Equal(t, c.platform, platform.Platform, c.out)
+			assert.Equal(t, c.arch, platform.Arch, c.out)
+		}
+	}
+
+	_, err := parseUname("uname: command not found")
+	assert.Error(t, err)
+}
+
+func TestParseWindowsArch(t *testing.T) {
+	platform, err := parseWindowsArch("AMD64")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "windows", platform.Platform)
+		assert.Equal(t, "amd64", platform.Arch)
+	}
+	platform, err = parseWindowsArch("ARM64")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "arm64", platform.Arch)
+	}
+	_, err = parseWindowsArch("")
+	assert.Error(t, err)
+}
+
+func TestRuntime_HostPlatform(t *testing.T) {
+	dir := t.TempDir()
+	db, err := openDB(zap.NewNop(), dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	options := newOptions()
+	options.logger = zap.NewNop()
+	rt := &Runtime{opts: options, db: db, platforms: map[string]*hostPlatform{}}
+
+	ctx := context.TODO()
+	conn := &unameClient{out: "Linux aarch64"}
+	platform, err := rt.hostPlatform(ctx, "web1", conn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "linux", platform.Platform)
+		assert.Equal(t, "arm64", platform.Arch)
+	}
+	assert.Equal(t, []string{"uname"}, conn.execs)
+
+	// the platform is loaded from db
+	rt.platforms = map[string]*hostPlatform{}
+	platform, err = rt.hostPlatform(ctx, "web1", conn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "arm64", platform.Arch)
+	}
+	assert.Len(t, conn.execs, 1)
+
+	// the platform is detected again after reset
+	assert.NoError(t, rt.ResetPlatform("web1"))
+	conn.out = "Linux x86_64"
+	platform, err = rt.hostPlatform(ctx, "web1", conn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "amd64", platform.Arch)
+	}
+	assert.Len(t, conn.execs, 2)
+}
+
+func TestRuntime_ApplyStableMap(t *testing.T) {
+	dir := t.TempDir()
+	db, err := openDB(zap.NewNop(), dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	dataloader := parser.NewDataLoader()
+	if err = dataloader.ParseString("web1\nweb2 bee_platform=linux bee_arch=arm64\n"); err != nil {
+		t.Fatal(err)
+	}
+	options := newOptions()
+	options.logger = zap.NewNop()
+	rt := &Runtime{
+		opts:      options,
+		db:        db,
+		variables: vars.NewVariablesManager(dataloader, nil),
+		platforms: map[string]*hostPlatform{},
+	}
+
+	// the failed detection isn't cached
+	ctx := context.TODO()
+	conn := &unameClient{out: "unknown"}
+	_, err = rt.applyStableMap(ctx, "web1", conn)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "host 'web1'")
+	}
+	assert.Empty(t, rt.platforms)
+	conn.out = "Linux aarch64"
+	sm, err := rt.applyStableMap(ctx, "web1", conn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "linux", sm.GetDefault(vars.BeePlatformVars, ""))
+		assert.Equal(t, "arm64", sm.GetDefault(vars.BeeArchVars, ""))
+	}
+	assert.Len(t, conn.execs, 2)
+
+	// the platform in inventory isn't detected
+	conn = &unameClient{out: "unknown"}
+	sm, err = rt.applyStableMap(ctx, "web2", conn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "arm64", sm.GetDefault(vars.BeeArchVars, ""))
+	}
+	assert.Empty(t, conn.execs)
+}
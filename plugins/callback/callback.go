@@ -17,12 +17,88 @@
 
 package callback
 
-import "github.com/olive-io/bee/stats"
+import (
+	"context"
 
+	"github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// ICallBack receives the events of process. The implementations embed BaseCallBack
+// to ignore the events they don't care about.
 type ICallBack interface {
+	// PlayOnStart is called before the process runs
+	PlayOnStart(pr *process.Process)
+	// PlayOnEnd is called after the process runs, as counts the task results of hosts
+	PlayOnEnd(pr *process.Process, as *executor.AggregateStats)
+	// TaskOnStart is called before the task runs on the hosts
+	TaskOnStart(task process.INamedTask, hosts []string)
+	// HandlerOnStart is called before the notified handler runs on the hosts
+	HandlerOnStart(handler *process.Handler, hosts []string)
+	// HandlerOnCatch is called before the catch handler of task runs on the hosts, after the process fails
+	HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string)
+	// HandlerOnFinish is called before the finish handler of task runs on the hosts
+	HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string)
+
+	// RunnerOnUnreachable is called when the connection of host fails
 	RunnerOnUnreachable(result *stats.TaskResult)
 	RunnerOnOk(result *stats.TaskResult)
 	RunnerOkFailed(result *stats.TaskResult)
+	// RunnerOnSkipped is called when the task is skipped on the host, e.g. the condition of task is false
+	RunnerOnSkipped(result *stats.TaskResult)
+	// RunnerOnRetry is called when the task fails on the host and runs again, see stats.TaskResult Attempts
+	RunnerOnRetry(result *stats.TaskResult)
+	// RunnerOnTransfer is called when the files are uploaded to the host, e.g. the modules
+	RunnerOnTransfer(host string, trace *client.IOTrace)
+}
+
+// IFlusher is implemented by the callbacks which buffer the events, e.g. Webhook.
+// The buffered events are flushed by Runtime.Stop.
+type IFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// ICloser is implemented by the callbacks which hold the resources, e.g. the file of
+// JSONLines and the goroutine of Webhook. They are closed by Runtime.Stop after flushing.
+type ICloser interface {
+	Close(ctx context.Context) error
+}
+
+// Flushers returns the callbacks implement IFlusher, the composite callbacks are unfolded
+func Flushers(cb ICallBack) []IFlusher {
+	flushers := make([]IFlusher, 0)
+	for _, item := range unfold(cb) {
+		if flusher, ok := item.(IFlusher); ok {
+			flushers = append(flushers, flusher)
+		}
+	}
+	return flushers
+}
+
+// Closers returns the callbacks implement ICloser, the composite callbacks are unfolded
+func Closers(cb ICallBack) []ICloser {
+	closers := make([]ICloser, 0)
+	for _, item := range unfold(cb) {
+		if closer, ok := item.(ICloser); ok {
+			closers = append(closers, closer)
+		}
+	}
+	return closers
+}
+
+// unfold returns the callbacks in the composite callbacks
+func unfold(cb ICallBack) []ICallBack {
+	composite, ok := cb.(*CompositeCallBack)
+	if !ok {
+		return []ICallBack{cb}
+	}
+	callbacks := make([]ICallBack, 0, len(composite.callbacks))
+	for _, item := range composite.callbacks {
+		callbacks = append(callbacks, unfold(item)...)
+	}
+	return callbacks
 }
 
 func NewCallBack() ICallBack {
@@ -32,6 +108,24 @@ func NewCallBack() ICallBack {
 type BaseCallBack struct {
 }
 
+func (b *BaseCallBack) PlayOnStart(pr *process.Process) {
+}
+
+func (b *BaseCallBack) PlayOnEnd(pr *process.Process, as *executor.AggregateStats) {
+}
+
+func (b *BaseCallBack) TaskOnStart(task process.INamedTask, hosts []string) {
+}
+
+func (b *BaseCallBack) HandlerOnStart(handler *process.Handler, hosts []string) {
+}
+
+func (b *BaseCallBack) HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string) {
+}
+
+func (b *BaseCallBack) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+}
+
 func (b *BaseCallBack) RunnerOnUnreachable(result *stats.TaskResult) {
 }
 
@@ -40,3 +134,12 @@ func (b *BaseCallBack) RunnerOnOk(result *stats.TaskResult) {
 
 func (b *BaseCallBack) RunnerOkFailed(result *stats.TaskResult) {
 }
+
+func (b *BaseCallBack) RunnerOnSkipped(result *stats.TaskResult) {
+}
+
+func (b *BaseCallBack) RunnerOnRetry(result *stats.TaskResult) {
+}
+
+func (b *BaseCallBack) RunnerOnTransfer(host string, trace *client.IOTrace) {
+}
@@ -0,0 +1,70 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/olive-io/bee/stats"
+)
+
+type okRecorder struct {
+	BaseCallBack
+
+	name  string
+	hosts *[]string
+}
+
+func (r *okRecorder) RunnerOnOk(result *stats.TaskResult) {
+	*r.hosts = append(*r.hosts, r.name+"@"+result.Host)
+}
+
+func TestCompose(t *testing.T) {
+	hosts := make([]string, 0)
+	a := &okRecorder{name: "a", hosts: &hosts}
+	b := &okRecorder{name: "b", hosts: &hosts}
+	c := &okRecorder{name: "c", hosts: &hosts}
+
+	assert.Equal(t, NewCallBack(), Compose(nil, NewCallBack()))
+	assert.Equal(t, a, Compose(nil, a))
+	assert.Equal(t, a, Compose((*BaseCallBack)(nil), (*okRecorder)(nil), (*CompositeCallBack)(nil), a))
+
+	cb := Compose(Compose(a, b), NewCallBack(), c)
+	if composite, ok := cb.(*CompositeCallBack); assert.True(t, ok) {
+		assert.Len(t, composite.CallBacks(), 3)
+	}
+	cb.RunnerOnOk(&stats.TaskResult{Host: "h1"})
+	assert.Equal(t, []string{"a@h1", "b@h1", "c@h1"}, hosts)
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test-recorder", func(options map[string]any) (ICallBack, error) {
+		return &okRecorder{name: options["name"].(string)}, nil
+	})
+	assert.Contains(t, Registered(), "test-recorder")
+
+	cb, err := Build("test-recorder", map[string]any{"name": "a"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "a", cb.(*okRecorder).name)
+	}
+
+	_, err = Build("unknown", nil)
+	assert.EqualError(t, err, "unknown callback plugin 'unknown'")
+}
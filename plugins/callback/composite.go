@@ -0,0 +1,153 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"reflect"
+
+	"github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// CompositeCallBack forwards the events to the callbacks in order
+type CompositeCallBack struct {
+	callbacks []ICallBack
+}
+
+// Compose returns the ICallBack which forwards the events to all callbacks in order.
+// The nil callbacks, including the typed nil pointers, and BaseCallBack are dropped,
+// the composite callbacks are flattened.
+func Compose(callbacks ...ICallBack) ICallBack {
+	flat := flatten(make([]ICallBack, 0, len(callbacks)), callbacks)
+
+	switch len(flat) {
+	case 0:
+		return NewCallBack()
+	case 1:
+		return flat[0]
+	}
+	return &CompositeCallBack{callbacks: flat}
+}
+
+func flatten(flat, callbacks []ICallBack) []ICallBack {
+	for _, cb := range callbacks {
+		if isNil(cb) {
+			continue
+		}
+		switch tt := cb.(type) {
+		case *BaseCallBack:
+		case *CompositeCallBack:
+			flat = flatten(flat, tt.callbacks)
+		default:
+			flat = append(flat, cb)
+		}
+	}
+	return flat
+}
+
+// isNil reports whether cb is nil or holds a nil pointer, e.g. (*BaseCallBack)(nil)
+func isNil(cb ICallBack) bool {
+	if cb == nil {
+		return true
+	}
+	rv := reflect.ValueOf(cb)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// CallBacks returns the callbacks of composite
+func (c *CompositeCallBack) CallBacks() []ICallBack {
+	return append([]ICallBack{}, c.callbacks...)
+}
+
+func (c *CompositeCallBack) PlayOnStart(pr *process.Process) {
+	for _, cb := range c.callbacks {
+		cb.PlayOnStart(pr)
+	}
+}
+
+func (c *CompositeCallBack) PlayOnEnd(pr *process.Process, as *executor.AggregateStats) {
+	for _, cb := range c.callbacks {
+		cb.PlayOnEnd(pr, as)
+	}
+}
+
+func (c *CompositeCallBack) TaskOnStart(task process.INamedTask, hosts []string) {
+	for _, cb := range c.callbacks {
+		cb.TaskOnStart(task, hosts)
+	}
+}
+
+func (c *CompositeCallBack) HandlerOnStart(handler *process.Handler, hosts []string) {
+	for _, cb := range c.callbacks {
+		cb.HandlerOnStart(handler, hosts)
+	}
+}
+
+func (c *CompositeCallBack) HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string) {
+	for _, cb := range c.callbacks {
+		cb.HandlerOnCatch(task, handler, hosts)
+	}
+}
+
+func (c *CompositeCallBack) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+	for _, cb := range c.callbacks {
+		cb.HandlerOnFinish(task, handler, hosts)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOnUnreachable(result *stats.TaskResult) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOnUnreachable(result)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOnOk(result *stats.TaskResult) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOnOk(result)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOkFailed(result *stats.TaskResult) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOkFailed(result)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOnSkipped(result *stats.TaskResult) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOnSkipped(result)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOnRetry(result *stats.TaskResult) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOnRetry(result)
+	}
+}
+
+func (c *CompositeCallBack) RunnerOnTransfer(host string, trace *client.IOTrace) {
+	for _, cb := range c.callbacks {
+		cb.RunnerOnTransfer(host, trace)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+
+	"github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+const (
+	EventPlayStart         = "play_start"
+	EventPlayEnd           = "play_end"
+	EventTaskStart         = "task_start"
+	EventHandlerStart      = "handler_start"
+	EventHandlerCatch      = "handler_catch"
+	EventHandlerFinish     = "handler_finish"
+	EventRunnerUnreachable = "runner_unreachable"
+	EventRunnerOk          = "runner_ok"
+	EventRunnerFailed      = "runner_failed"
+	EventRunnerSkipped     = "runner_skipped"
+	EventRunnerRetry       = "runner_retry"
+	EventRunnerTransfer    = "runner_transfer"
+)
+
+// TransferInterval is the minimum interval between the progress events of a transfer,
+// the first and the last events of the transfer are always emitted
+const TransferInterval = time.Second
+
+// Event is a line of JSONLines
+type Event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+
+	PlayId string `json:"play_id,omitempty"`
+	Play   string `json:"play,omitempty"`
+	// TaskId and Task are the id and name of task or handler
+	TaskId string   `json:"task_id,omitempty"`
+	Task   string   `json:"task,omitempty"`
+	Hosts  []string `json:"hosts,omitempty"`
+
+	Result *stats.TaskResult `json:"result,omitempty"`
+	// Duration is the seconds from the start of task to the result of host
+	Duration float64 `json:"duration,omitempty"`
+
+	Transfer *client.IOTrace `json:"transfer,omitempty"`
+	// Stats is the summary of hosts at the end of play
+	Stats map[string]map[string]int64 `json:"stats,omitempty"`
+}
+
+// JSONLines writes every event as a json line to the writer
+type JSONLines struct {
+	emitter
+
+	wmu sync.Mutex
+	w   io.Writer
+	// c is the file of events which JSONLines opens, it is closed by Close
+	c io.Closer
+}
+
+func NewJSONLines(w io.Writer) *JSONLines {
+	j := &JSONLines{w: w}
+	j.emitter.setup(j.write)
+	return j
+}
+
+// newJSONLinesPlugin creates JSONLines by the options, "path" is the file of events
+// appended to, the events are written to stdout without it.
+func newJSONLinesPlugin(options map[string]any) (ICallBack, error) {
+	path, _ := options["path"].(string)
+	if path == "" || path == "-" {
+		return NewJSONLines(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open events file: %w", err)
+	}
+	j := NewJSONLines(f)
+	j.c = f
+	return j, nil
+}
+
+func init() {
+	Register("jsonl", newJSONLinesPlugin)
+}
+
+// Close closes the file of events, the later events are dropped
+func (j *JSONLines) Close(ctx context.Context) error {
+	j.wmu.Lock()
+	defer j.wmu.Unlock()
+	if j.c == nil {
+		return nil
+	}
+	err := j.c.Close()
+	j.c = nil
+	j.w = io.Discard
+	return err
+}
+
+func (j *JSONLines) write(event *Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	j.wmu.Lock()
+	defer j.wmu.Unlock()
+	_, _ = j.w.Write(data)
+}
+
+// emitter converts the callbacks to events with the timings of tasks, the events are handled by emit
+type emitter struct {
+	mu sync.Mutex
+	// starts records the start time of tasks by the id
+	starts map[string]time.Time
+	// transfers records the time of the last progress event of transfers by the host and destination
+	transfers map[string]time.Time
+	now       func() time.Time
+	emit      func(event *Event)
+}
+
+func (em *emitter) setup(emit func(event *Event)) {
+	em.starts = map[string]time.Time{}
+	em.transfers = map[string]time.Time{}
+	em.now = time.Now
+	em.emit = emit
+}
+
+func (em *emitter) start(event, id, name string, hosts []string) {
+	now := em.now()
+	em.mu.Lock()
+	em.starts[id] = now
+	em.mu.Unlock()
+
+	em.emit(&Event{Event: event, Time: now, TaskId: id, Task: name, Hosts: hosts})
+}
+
+func (em *emitter) result(event string, result *stats.TaskResult) {
+	now := em.now()
+	em.mu.Lock()
+	start, ok := em.starts[result.TaskId]
+	em.mu.Unlock()
+
+	e := &Event{
+		Event:  event,
+		Time:   now,
+		TaskId: result.TaskId,
+		Task:   result.Task,
+		Hosts:  []string{result.Host},
+		Result: result,
+	}
+	if ok {
+		e.Duration = now.Sub(start).Seconds()
+	}
+	em.emit(e)
+}
+
+func (em *emitter) PlayOnStart(pr *process.Process) {
+	em.emit(&Event{Event: EventPlayStart, Time: em.now(), PlayId: pr.Id, Play: pr.Name, Hosts: pr.Hosts})
+}
+
+func (em *emitter) PlayOnEnd(pr *process.Process, as *executor.AggregateStats) {
+	e := &Event{Event: EventPlayEnd, Time: em.now(), PlayId: pr.Id, Play: pr.Name}
+	if as != nil {
+		e.Stats = map[string]map[string]int64{}
+		for _, host := range as.Hosts() {
+			e.Stats[host] = as.Summarize(host)
+		}
+	}
+	em.emit(e)
+
+	em.mu.Lock()
+	em.starts = map[string]time.Time{}
+	em.transfers = map[string]time.Time{}
+	em.mu.Unlock()
+}
+
+func (em *emitter) TaskOnStart(task process.INamedTask, hosts []string) {
+	em.start(EventTaskStart, task.GetId(), task.GetName(), hosts)
+}
+
+func (em *emitter) HandlerOnStart(handler *process.Handler, hosts []string) {
+	em.start(EventHandlerStart, handler.Id, handler.Name, hosts)
+}
+
+func (em *emitter) HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string) {
+	em.start(EventHandlerCatch, handler.Id, handler.Name, hosts)
+}
+
+func (em *emitter) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+	em.start(EventHandlerFinish, handler.Id, handler.Name, hosts)
+}
+
+func (em *emitter) RunnerOnUnreachable(result *stats.TaskResult) {
+	em.result(EventRunnerUnreachable, result)
+}
+
+func (em *emitter) RunnerOnOk(result *stats.TaskResult) {
+	em.result(EventRunnerOk, result)
+}
+
+func (em *emitter) RunnerOkFailed(result *stats.TaskResult) {
+	em.result(EventRunnerFailed, result)
+}
+
+func (em *emitter) RunnerOnSkipped(result *stats.TaskResult) {
+	em.result(EventRunnerSkipped, result)
+}
+
+func (em *emitter) RunnerOnRetry(result *stats.TaskResult) {
+	em.result(EventRunnerRetry, result)
+}
+
+// RunnerOnTransfer emits the progress of transfer at most once every TransferInterval.
+// The trace is reused by the client for the whole transfer, so the event takes a copy of it.
+func (em *emitter) RunnerOnTransfer(host string, trace *client.IOTrace) {
+	now := em.now()
+	key := host + "\x00" + trace.Src + "\x00" + trace.Dst
+	done := trace.Total > 0 && trace.Chunk >= trace.Total
+
+	em.mu.Lock()
+	last, ok := em.transfers[key]
+	if ok && !done && now.Sub(last) < TransferInterval {
+		em.mu.Unlock()
+		return
+	}
+	if done {
+		delete(em.transfers, key)
+	} else {
+		em.transfers[key] = now
+	}
+	em.mu.Unlock()
+
+	transfer := *trace
+	em.emit(&Event{Event: EventRunnerTransfer, Time: now, Hosts: []string{host}, Transfer: &transfer})
+}
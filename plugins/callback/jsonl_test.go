@@ -0,0 +1,136 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// fakeClock returns the times forward a second for every call
+func fakeClock() func() time.Time {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	cb := NewJSONLines(buf)
+	cb.now = fakeClock()
+
+	pr := &process.Process{Name: "site", Id: "p1", Hosts: []string{"h1"}}
+	task := &process.Task{Name: "ping", Id: "t1"}
+	as := executor.NewStats()
+	as.Increment(executor.Ok, "h1")
+
+	cb.PlayOnStart(pr)
+	cb.TaskOnStart(task, []string{"h1"})
+	cb.RunnerOnOk(&stats.TaskResult{Host: "h1", Task: "ping", TaskId: "t1", Stdout: map[string]any{"message": "pong"}})
+	cb.PlayOnEnd(pr, as)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+
+	events := make([]*Event, 0, len(lines))
+	for _, line := range lines {
+		event := &Event{}
+		require.NoError(t, json.Unmarshal([]byte(line), event))
+		events = append(events, event)
+	}
+	assert.Equal(t, EventPlayStart, events[0].Event)
+	assert.Equal(t, "site", events[0].Play)
+	assert.Equal(t, EventTaskStart, events[1].Event)
+	assert.Equal(t, "t1", events[1].TaskId)
+
+	ok := events[2]
+	assert.Equal(t, EventRunnerOk, ok.Event)
+	assert.Equal(t, "ping", ok.Task)
+	assert.Equal(t, 1.0, ok.Duration)
+	if assert.NotNil(t, ok.Result) {
+		assert.Equal(t, "pong", ok.Result.Stdout["message"])
+	}
+
+	assert.Equal(t, EventPlayEnd, events[3].Event)
+	assert.Equal(t, int64(1), events[3].Stats["h1"]["ok"])
+}
+
+func TestJSONLines_Close(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	cb, err := newJSONLinesPlugin(map[string]any{"path": path})
+	require.NoError(t, err)
+
+	// the file is closed by Runtime.Stop through Closers
+	closers := Closers(Compose(&BaseCallBack{}, cb))
+	require.Len(t, closers, 1)
+
+	pr := &process.Process{Name: "site", Id: "p1"}
+	cb.PlayOnStart(pr)
+	assert.NoError(t, closers[0].Close(context.TODO()))
+	assert.NoError(t, closers[0].Close(context.TODO()))
+	// the events after closing are dropped
+	cb.PlayOnEnd(pr, nil)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+}
+
+func TestJSONLines_Transfer(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	cb := NewJSONLines(buf)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb.now = func() time.Time { return now }
+
+	trace := &client.IOTrace{Name: "a.tar", Src: "a.tar", Dst: "/tmp/a.tar", Total: 4}
+	for i := int64(1); i <= 4; i++ {
+		trace.Chunk = i
+		cb.RunnerOnTransfer("h1", trace)
+		now = now.Add(TransferInterval / 2)
+	}
+	// the client reuses the trace after the transfer
+	trace.Chunk = 0
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// the first, the throttled third and the last events
+	require.Len(t, lines, 3)
+	chunks := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		event := &Event{}
+		require.NoError(t, json.Unmarshal([]byte(line), event))
+		assert.Equal(t, EventRunnerTransfer, event.Event)
+		chunks = append(chunks, event.Transfer.Chunk)
+	}
+	assert.Equal(t, []int64{1, 3, 4}, chunks)
+}
@@ -0,0 +1,254 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+// DefaultJUnitSuite is the name of testsuite of results reported outside of play
+const DefaultJUnitSuite = "bee"
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Errors   int           `xml:"errors,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     float64       `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string       `xml:"name,attr"`
+	Id        string       `xml:"id,attr,omitempty"`
+	Tests     int          `xml:"tests,attr"`
+	Failures  int          `xml:"failures,attr"`
+	Errors    int          `xml:"errors,attr"`
+	Skipped   int          `xml:"skipped,attr"`
+	Time      float64      `xml:"time,attr"`
+	Timestamp string       `xml:"timestamp,attr"`
+	Cases     []*junitCase `xml:"testcase"`
+
+	start time.Time
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit reports the results as JUnit XML, a testsuite per play and a testcase per task and host.
+// The failed testcases carry the error message and stderr of module, the unreachable hosts
+// are reported as errors.
+type JUnit struct {
+	BaseCallBack
+
+	mu     sync.Mutex
+	output string
+	suites []*junitSuite
+	suite  *junitSuite
+	// starts records the start time of tasks by the id
+	starts map[string]time.Time
+	now    func() time.Time
+}
+
+// NewJUnit creates JUnit, the report is rewritten to the output file at the end of every play,
+// it is kept in memory only when output is empty, see WriteXML.
+func NewJUnit(output string) *JUnit {
+	return &JUnit{
+		output: output,
+		starts: map[string]time.Time{},
+		now:    time.Now,
+	}
+}
+
+func newJUnitPlugin(options map[string]any) (ICallBack, error) {
+	path, _ := options["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("missing option 'path'")
+	}
+	return NewJUnit(path), nil
+}
+
+func init() {
+	Register("junit", newJUnitPlugin)
+}
+
+// WriteXML writes the report of all plays to the writer
+func (j *JUnit) WriteXML(w io.Writer) error {
+	j.mu.Lock()
+	report := &junitSuites{Suites: j.suites}
+	for _, suite := range j.suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Time += suite.Time
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err = w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (j *JUnit) flush() error {
+	f, err := os.Create(j.output)
+	if err != nil {
+		return err
+	}
+	if err = j.WriteXML(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (j *JUnit) newSuite(name, id string) *junitSuite {
+	now := j.now()
+	suite := &junitSuite{
+		Name:      name,
+		Id:        id,
+		Timestamp: now.Format("2006-01-02T15:04:05"),
+		start:     now,
+	}
+	j.suites = append(j.suites, suite)
+	return suite
+}
+
+func (j *JUnit) PlayOnStart(pr *process.Process) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.suite = j.newSuite(pr.Name, pr.Id)
+}
+
+func (j *JUnit) PlayOnEnd(pr *process.Process, as *executor.AggregateStats) {
+	j.mu.Lock()
+	if j.suite != nil {
+		j.suite.Time = j.now().Sub(j.suite.start).Seconds()
+	}
+	j.suite = nil
+	j.starts = map[string]time.Time{}
+	j.mu.Unlock()
+
+	if j.output != "" {
+		_ = j.flush()
+	}
+}
+
+func (j *JUnit) start(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.starts[id] = j.now()
+}
+
+func (j *JUnit) TaskOnStart(task process.INamedTask, hosts []string) {
+	j.start(task.GetId())
+}
+
+func (j *JUnit) HandlerOnStart(handler *process.Handler, hosts []string) {
+	j.start(handler.Id)
+}
+
+func (j *JUnit) HandlerOnCatch(task process.INamedTask, handler *process.Handler, hosts []string) {
+	j.start(handler.Id)
+}
+
+func (j *JUnit) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+	j.start(handler.Id)
+}
+
+// add appends the testcase of result to the current suite
+func (j *JUnit) add(result *stats.TaskResult, fn func(suite *junitSuite, tc *junitCase)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	suite := j.suite
+	if suite == nil {
+		suite = j.newSuite(DefaultJUnitSuite, "")
+		j.suite = suite
+	}
+	name := result.Task
+	if name == "" {
+		name = result.TaskId
+	}
+	tc := &junitCase{
+		Name:      fmt.Sprintf("%s [%s]", name, result.Host),
+		ClassName: suite.Name,
+	}
+	if start, ok := j.starts[result.TaskId]; ok {
+		tc.Time = j.now().Sub(start).Seconds()
+	}
+	fn(suite, tc)
+	suite.Tests += 1
+	suite.Cases = append(suite.Cases, tc)
+}
+
+func (j *JUnit) RunnerOnUnreachable(result *stats.TaskResult) {
+	j.add(result, func(suite *junitSuite, tc *junitCase) {
+		suite.Errors += 1
+		tc.Error = &junitMessage{Message: result.ErrMsg, Type: "unreachable", Text: result.Stderr}
+	})
+}
+
+func (j *JUnit) RunnerOnOk(result *stats.TaskResult) {
+	j.add(result, func(suite *junitSuite, tc *junitCase) {})
+}
+
+func (j *JUnit) RunnerOkFailed(result *stats.TaskResult) {
+	j.add(result, func(suite *junitSuite, tc *junitCase) {
+		suite.Failures += 1
+		tc.Failure = &junitMessage{Message: result.ErrMsg, Type: "failed", Text: result.Stderr}
+	})
+}
+
+func (j *JUnit) RunnerOnSkipped(result *stats.TaskResult) {
+	j.add(result, func(suite *junitSuite, tc *junitCase) {
+		suite.Skipped += 1
+		reason, _ := result.Stdout["skip_reason"].(string)
+		tc.Skipped = &junitMessage{Message: reason}
+	})
+}
@@ -0,0 +1,75 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+)
+
+func TestJUnit(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "report.xml")
+	cb := NewJUnit(output)
+	cb.now = fakeClock()
+
+	pr := &process.Process{Name: "site", Id: "p1"}
+	task := &process.Task{Name: "install", Id: "t1"}
+
+	cb.PlayOnStart(pr)
+	cb.TaskOnStart(task, []string{"h1", "h2", "h3"})
+	cb.RunnerOnOk(&stats.TaskResult{Host: "h1", Task: "install", TaskId: "t1"})
+	cb.RunnerOkFailed(&stats.TaskResult{Host: "h2", Task: "install", TaskId: "t1", ErrMsg: "exit status 1: no such package", Stderr: "no such package"})
+	cb.RunnerOnSkipped(&stats.TaskResult{Host: "h3", Task: "install", TaskId: "t1", Stdout: map[string]any{"skip_reason": "conditional result was false"}})
+	cb.PlayOnEnd(pr, nil)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	report := &junitSuites{}
+	require.NoError(t, xml.Unmarshal(data, report))
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Suites, 1)
+
+	suite := report.Suites[0]
+	assert.Equal(t, "site", suite.Name)
+	require.Len(t, suite.Cases, 3)
+	assert.Equal(t, "install [h1]", suite.Cases[0].Name)
+	assert.Nil(t, suite.Cases[0].Failure)
+	if failure := suite.Cases[1].Failure; assert.NotNil(t, failure) {
+		assert.Equal(t, "exit status 1: no such package", failure.Message)
+		assert.Equal(t, "no such package", failure.Text)
+	}
+	if skipped := suite.Cases[2].Skipped; assert.NotNil(t, skipped) {
+		assert.Equal(t, "conditional result was false", skipped.Message)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, cb.WriteXML(buf))
+	assert.Equal(t, string(data), buf.String())
+}
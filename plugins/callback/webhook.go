@@ -0,0 +1,351 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of request body, likes "sha256=<hex>"
+	SignatureHeader = "X-Bee-Signature"
+
+	DefaultWebhookBufferSize    = 1000
+	DefaultWebhookBatchSize     = 50
+	DefaultWebhookFlushInterval = time.Second
+	DefaultWebhookMaxRetries    = 3
+	DefaultWebhookBackoff       = time.Millisecond * 500
+	DefaultWebhookMaxBackoff    = time.Second * 30
+	DefaultWebhookTimeout       = time.Second * 5
+)
+
+type WebhookOptions struct {
+	URL string
+	// Secret signs the request body by HMAC-SHA256, see SignatureHeader
+	Secret  string
+	Headers map[string]string
+	// BufferSize is the max number of buffered events, the oldest events are dropped when it is full
+	BufferSize int
+	// BatchSize is the max number of events in a request
+	BatchSize int
+	// FlushInterval is the interval of sending the buffered events
+	FlushInterval time.Duration
+	// MaxRetries is the number of retries of failed request, the wait time starts at Backoff
+	// and doubles after every retry, up to MaxBackoff. The webhook plugin retries DefaultWebhookMaxRetries
+	// times without "max_retries" option.
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Timeout    time.Duration
+	Client     *http.Client
+}
+
+func (o *WebhookOptions) setDefaults() {
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultWebhookBufferSize
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultWebhookBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultWebhookFlushInterval
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultWebhookBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultWebhookTimeout
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: o.Timeout}
+	}
+}
+
+// Webhook POSTs the events as json array to the url. The events are buffered in memory
+// and sent in batches by the background goroutine, the failed requests are retried with
+// exponential backoff.
+type Webhook struct {
+	emitter
+
+	opts WebhookOptions
+
+	bmu     sync.Mutex
+	buffer  []*Event
+	dropped int
+
+	// smu serializes the requests, keeps the events in order
+	smu  sync.Mutex
+	kick chan struct{}
+	stop chan struct{}
+	once sync.Once
+	done chan struct{}
+}
+
+func NewWebhook(opts WebhookOptions) (*Webhook, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("missing webhook url")
+	}
+	opts.setDefaults()
+
+	w := &Webhook{
+		opts: opts,
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	w.emitter.setup(w.enqueue)
+	go w.loop()
+	return w, nil
+}
+
+func newWebhookPlugin(options map[string]any) (ICallBack, error) {
+	opts := WebhookOptions{
+		MaxRetries: DefaultWebhookMaxRetries,
+		Headers:    map[string]string{},
+	}
+	opts.URL, _ = options["url"].(string)
+	opts.Secret, _ = options["secret"].(string)
+	if headers, ok := options["headers"].(map[string]any); ok {
+		for key, value := range headers {
+			opts.Headers[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	var err error
+	if opts.BufferSize, err = intOption(options, "buffer_size"); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize, err = intOption(options, "batch_size"); err != nil {
+		return nil, err
+	}
+	if _, ok := options["max_retries"]; ok {
+		if opts.MaxRetries, err = intOption(options, "max_retries"); err != nil {
+			return nil, err
+		}
+	}
+	if opts.FlushInterval, err = durationOption(options, "flush_interval"); err != nil {
+		return nil, err
+	}
+	if opts.Backoff, err = durationOption(options, "backoff"); err != nil {
+		return nil, err
+	}
+	if opts.MaxBackoff, err = durationOption(options, "max_backoff"); err != nil {
+		return nil, err
+	}
+	if opts.Timeout, err = durationOption(options, "timeout"); err != nil {
+		return nil, err
+	}
+
+	return NewWebhook(opts)
+}
+
+func init() {
+	Register("webhook", newWebhookPlugin)
+}
+
+func intOption(options map[string]any, key string) (int, error) {
+	switch tt := options[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return tt, nil
+	case int64:
+		return int(tt), nil
+	case float64:
+		return int(tt), nil
+	default:
+		return 0, fmt.Errorf("option '%s' is not a number", key)
+	}
+}
+
+// durationOption parses the duration likes "1s", the number is the seconds
+func durationOption(options map[string]any, key string) (time.Duration, error) {
+	switch tt := options[key].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		d, err := time.ParseDuration(tt)
+		if err != nil {
+			return 0, fmt.Errorf("option '%s': %w", key, err)
+		}
+		return d, nil
+	case int:
+		return time.Duration(tt) * time.Second, nil
+	case float64:
+		return time.Duration(tt * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("option '%s' is not a duration", key)
+	}
+}
+
+// Dropped returns the number of events dropped, because the buffer is full or the requests fail
+func (w *Webhook) Dropped() int {
+	w.bmu.Lock()
+	defer w.bmu.Unlock()
+	return w.dropped
+}
+
+func (w *Webhook) enqueue(event *Event) {
+	w.bmu.Lock()
+	if len(w.buffer) >= w.opts.BufferSize {
+		w.buffer = w.buffer[1:]
+		w.dropped += 1
+	}
+	w.buffer = append(w.buffer, event)
+	full := len(w.buffer) >= w.opts.BatchSize
+	w.bmu.Unlock()
+
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *Webhook) loop() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+		_ = w.Flush(context.Background())
+	}
+}
+
+// Flush sends all buffered events
+func (w *Webhook) Flush(ctx context.Context) error {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	for {
+		w.bmu.Lock()
+		n := len(w.buffer)
+		if n > w.opts.BatchSize {
+			n = w.opts.BatchSize
+		}
+		batch := w.buffer[:n:n]
+		w.buffer = w.buffer[n:]
+		w.bmu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := w.send(ctx, batch); err != nil {
+			w.bmu.Lock()
+			w.dropped += len(batch)
+			w.bmu.Unlock()
+			return err
+		}
+	}
+}
+
+// Close stops the background goroutine and flushes the buffered events
+func (w *Webhook) Close(ctx context.Context) error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+	return w.Flush(ctx)
+}
+
+// send posts the batch of events, retries the request when it fails by network error,
+// the server error or too many requests.
+func (w *Webhook) send(ctx context.Context, batch []*Event) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > w.opts.MaxBackoff {
+			backoff = w.opts.MaxBackoff
+		}
+	}
+}
+
+// post sends the request, returns whether the request can be retried when it fails
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.opts.Headers {
+		req.Header.Set(key, value)
+	}
+	if w.opts.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.opts.Secret, body))
+	}
+
+	rsp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, rsp.Body)
+
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := rsp.StatusCode >= 500 || rsp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook responses %s", rsp.Status)
+}
+
+// Sign returns the value of SignatureHeader of the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/olive-io/bee/stats"
+)
+
+type webhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	requests int
+	batches  [][]*Event
+}
+
+func newWebhookServer(t *testing.T, failures int) *webhookServer {
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests += 1
+		if s.failures > 0 {
+			s.failures -= 1
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		batch := make([]*Event, 0)
+		require.NoError(t, json.Unmarshal(body, &batch))
+		s.batches = append(s.batches, batch)
+		assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestWebhook(t *testing.T) {
+	server := newWebhookServer(t, 2)
+
+	cb, err := NewWebhook(WebhookOptions{
+		URL:           server.URL,
+		Secret:        "secret",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		MaxRetries:    3,
+		Backoff:       time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for _, host := range []string{"h1", "h2", "h3"} {
+		cb.RunnerOnOk(&stats.TaskResult{Host: host, Task: "ping", TaskId: "t1"})
+	}
+	require.NoError(t, cb.Close(context.Background()))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// the first batch is sent after two failed requests
+	assert.Equal(t, 4, server.requests)
+	require.Len(t, server.batches, 2)
+	assert.Len(t, server.batches[0], 2)
+	assert.Len(t, server.batches[1], 1)
+	assert.Equal(t, EventRunnerOk, server.batches[1][0].Event)
+	assert.Equal(t, "h3", server.batches[1][0].Result.Host)
+	assert.Equal(t, 0, cb.Dropped())
+}
+
+func TestWebhook_Drop(t *testing.T) {
+	server := newWebhookServer(t, 10)
+
+	cb, err := NewWebhook(WebhookOptions{
+		URL:           server.URL,
+		BufferSize:    2,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		MaxRetries:    1,
+		Backoff:       time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for _, host := range []string{"h1", "h2", "h3"} {
+		cb.RunnerOnOk(&stats.TaskResult{Host: host})
+	}
+	// the oldest event is dropped by the full buffer, the others by failed requests
+	assert.Error(t, cb.Close(context.Background()))
+	assert.Equal(t, 3, cb.Dropped())
+	assert.Equal(t, []IFlusher{cb}, Flushers(Compose(NewJUnit(""), cb)))
+	// Runtime.Stop stops the background goroutine through Closers
+	assert.Equal(t, []ICloser{cb}, Closers(Compose(NewJUnit(""), cb)))
+}
@@ -0,0 +1,90 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package filter
+
+import (
+	"reflect"
+)
+
+// CompositeFilter chains the filters, the output of a filter is the input of next one
+type CompositeFilter struct {
+	filters []IFilter
+}
+
+// Compose returns the IFilter which chains all filters in order.
+// The nil filters, including the typed nil pointers, and BaseFilter are dropped,
+// the composite filters are flattened.
+func Compose(filters ...IFilter) IFilter {
+	flat := flatten(make([]IFilter, 0, len(filters)), filters)
+
+	switch len(flat) {
+	case 0:
+		return NewFilter()
+	case 1:
+		return flat[0]
+	}
+	return &CompositeFilter{filters: flat}
+}
+
+func flatten(flat, filters []IFilter) []IFilter {
+	for _, ft := range filters {
+		if isNil(ft) {
+			continue
+		}
+		switch tt := ft.(type) {
+		case *BaseFilter:
+		case *CompositeFilter:
+			flat = flatten(flat, tt.filters)
+		default:
+			flat = append(flat, ft)
+		}
+	}
+	return flat
+}
+
+// isNil reports whether ft is nil or holds a nil pointer, e.g. (*Redactor)(nil)
+func isNil(ft IFilter) bool {
+	if ft == nil {
+		return true
+	}
+	rv := reflect.ValueOf(ft)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// Filters returns the filters of composite
+func (c *CompositeFilter) Filters() []IFilter {
+	return append([]IFilter{}, c.filters...)
+}
+
+func (c *CompositeFilter) OnPreTaskProps(id string, pros, headers map[string]any) (map[string]any, map[string]any) {
+	for _, ft := range c.filters {
+		pros, headers = ft.OnPreTaskProps(id, pros, headers)
+	}
+	return pros, headers
+}
+
+func (c *CompositeFilter) OnPostTaskStdout(id string, stdout map[string]any) map[string]any {
+	for _, ft := range c.filters {
+		stdout = ft.OnPostTaskStdout(id, stdout)
+	}
+	return stdout
+}
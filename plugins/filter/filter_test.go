@@ -0,0 +1,66 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// suffixFilter appends the suffix to "msg" of stdout
+type suffixFilter struct {
+	BaseFilter
+
+	suffix string
+}
+
+func (f *suffixFilter) OnPostTaskStdout(id string, stdout map[string]any) map[string]any {
+	stdout["msg"] = stdout["msg"].(string) + f.suffix
+	return stdout
+}
+
+func TestCompose(t *testing.T) {
+	a := &suffixFilter{suffix: "a"}
+	assert.Equal(t, NewFilter(), Compose(nil, NewFilter()))
+	assert.Equal(t, a, Compose(a))
+	assert.Equal(t, a, Compose((*BaseFilter)(nil), (*Redactor)(nil), (*CompositeFilter)(nil), a))
+
+	ft := Compose(Compose(a, &suffixFilter{suffix: "b"}), NewFilter(), &suffixFilter{suffix: "c"})
+	stdout := ft.OnPostTaskStdout("t1", map[string]any{"msg": "-"})
+	assert.Equal(t, "-abc", stdout["msg"])
+
+	props, headers := ft.OnPreTaskProps("t1", map[string]any{"a": 1}, map[string]any{"b": 2})
+	assert.Equal(t, map[string]any{"a": 1}, props)
+	assert.Equal(t, map[string]any{"b": 2}, headers)
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test-suffix", func(options map[string]any) (IFilter, error) {
+		return &suffixFilter{suffix: options["suffix"].(string)}, nil
+	})
+	assert.Contains(t, Registered(), "test-suffix")
+
+	ft, err := Build("test-suffix", map[string]any{"suffix": "!"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "!", ft.(*suffixFilter).suffix)
+	}
+
+	_, err = Build("unknown", nil)
+	assert.EqualError(t, err, "unknown filter plugin 'unknown'")
+}
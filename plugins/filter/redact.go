@@ -0,0 +1,223 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package filter
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the secrets
+const RedactedValue = "********"
+
+// DefaultSecretKeys are the patterns of the names of secret variables, e.g. "bee_ssh_passwd"
+var DefaultSecretKeys = []string{"*_passwd", "*_passphrase"}
+
+// ISecretFilter is implemented by the filters which mask the secrets. The runtime adds the
+// secrets it knows before the tasks run, e.g. the passwords of hosts, and masks the logs by Redact.
+type ISecretFilter interface {
+	IFilter
+	// AddSecrets adds the values to mask
+	AddSecrets(values ...string)
+	// AddSecretVars adds the values of variables which names are secret
+	AddSecretVars(vars map[string]any)
+	// Redact masks the secrets in text
+	Redact(text string) string
+}
+
+// SecretFilters returns the filters implement ISecretFilter, the composite filters are unfolded
+func SecretFilters(ft IFilter) []ISecretFilter {
+	if composite, ok := ft.(*CompositeFilter); ok {
+		filters := make([]ISecretFilter, 0)
+		for _, item := range composite.filters {
+			filters = append(filters, SecretFilters(item)...)
+		}
+		return filters
+	}
+	if sf, ok := ft.(ISecretFilter); ok {
+		return []ISecretFilter{sf}
+	}
+	return nil
+}
+
+// Redactor masks the secrets in the stdout of tasks. The values of the fields which names match
+// the secret keys are masked entirely, the known secrets are masked wherever they appear.
+type Redactor struct {
+	mu sync.RWMutex
+	// keys are the patterns of secret names, see path.Match
+	keys    []string
+	secrets map[string]struct{}
+	// ordered are the secrets from longest to shortest, the longer one is masked first
+	ordered []string
+}
+
+// NewRedactor creates Redactor, the keys are appended to DefaultSecretKeys
+func NewRedactor(keys ...string) *Redactor {
+	return &Redactor{
+		keys:    append(append([]string{}, DefaultSecretKeys...), keys...),
+		secrets: map[string]struct{}{},
+	}
+}
+
+// newRedactorPlugin creates Redactor by the options, "keys" are the extra patterns of
+// secret names and "values" are the extra secrets.
+func newRedactorPlugin(options map[string]any) (IFilter, error) {
+	keys, err := stringsOption(options, "keys")
+	if err != nil {
+		return nil, err
+	}
+	values, err := stringsOption(options, "values")
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRedactor(keys...)
+	r.AddSecrets(values...)
+	return r, nil
+}
+
+func init() {
+	Register("redact", newRedactorPlugin)
+}
+
+func stringsOption(options map[string]any, key string) ([]string, error) {
+	switch tt := options[key].(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return tt, nil
+	case []any:
+		out := make([]string, 0, len(tt))
+		for _, item := range tt {
+			out = append(out, fmt.Sprintf("%v", item))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("option '%s' is not a list", key)
+	}
+}
+
+// IsSecretKey reports whether the name of field or variable is secret
+func (r *Redactor) IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) AddSecrets(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := false
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if _, ok := r.secrets[value]; ok {
+			continue
+		}
+		r.secrets[value] = struct{}{}
+		added = true
+	}
+	if !added {
+		return
+	}
+
+	ordered := make([]string, 0, len(r.secrets))
+	for value := range r.secrets {
+		ordered = append(ordered, value)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if len(ordered[i]) != len(ordered[j]) {
+			return len(ordered[i]) > len(ordered[j])
+		}
+		return ordered[i] < ordered[j]
+	})
+	r.ordered = ordered
+}
+
+func (r *Redactor) AddSecretVars(vars map[string]any) {
+	values := make([]string, 0)
+	for key, value := range vars {
+		switch tt := value.(type) {
+		case map[string]any:
+			r.AddSecretVars(tt)
+		case nil:
+		default:
+			if r.IsSecretKey(key) {
+				values = append(values, fmt.Sprintf("%v", tt))
+			}
+		}
+	}
+	r.AddSecrets(values...)
+}
+
+func (r *Redactor) Redact(text string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, value := range r.ordered {
+		text = strings.ReplaceAll(text, value, RedactedValue)
+	}
+	return text
+}
+
+// RedactValue returns the copy of value which the secrets are masked
+func (r *Redactor) RedactValue(value any) any {
+	switch tt := value.(type) {
+	case string:
+		return r.Redact(tt)
+	case []any:
+		out := make([]any, len(tt))
+		for i, item := range tt {
+			out[i] = r.RedactValue(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(tt))
+		for key, item := range tt {
+			if _, ok := item.(map[string]any); !ok && item != nil && r.IsSecretKey(key) {
+				out[key] = RedactedValue
+				continue
+			}
+			out[key] = r.RedactValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// OnPreTaskProps passes the props to the task as it is, the module needs the secrets
+func (r *Redactor) OnPreTaskProps(id string, pros, headers map[string]any) (map[string]any, map[string]any) {
+	return pros, headers
+}
+
+func (r *Redactor) OnPostTaskStdout(id string, stdout map[string]any) map[string]any {
+	if stdout == nil {
+		return nil
+	}
+	return r.RedactValue(stdout).(map[string]any)
+}
@@ -0,0 +1,66 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor("token")
+	r.AddSecretVars(map[string]any{
+		"bee_ssh_passwd":     "p@ss",
+		"bee_ssh_passphrase": "phrase",
+		"bee_user":           "root",
+		"db":                 map[string]any{"admin_passwd": "p@ss-admin"},
+	})
+	r.AddSecrets("", "sk-123")
+
+	assert.True(t, r.IsSecretKey("BEE_WINRM_PASSWD"))
+	assert.False(t, r.IsSecretKey("bee_user"))
+	assert.Equal(t, "login root:******** with ******** and ********", r.Redact("login root:p@ss with p@ss-admin and sk-123"))
+
+	stdout := r.OnPostTaskStdout("t1", map[string]any{
+		"msg":     "connected by p@ss",
+		"token":   "abc",
+		"changed": true,
+		"results": []any{map[string]any{"out": "phrase", "code": 0}},
+	})
+	assert.Equal(t, map[string]any{
+		"msg":     "connected by ********",
+		"token":   RedactedValue,
+		"changed": true,
+		"results": []any{map[string]any{"out": RedactedValue, "code": 0}},
+	}, stdout)
+}
+
+func TestSecretFilters(t *testing.T) {
+	r := NewRedactor()
+	ft := Compose(&suffixFilter{suffix: "a"}, r)
+	assert.Equal(t, []ISecretFilter{r}, SecretFilters(ft))
+	assert.Empty(t, SecretFilters(NewFilter()))
+
+	built, err := Build("redact", map[string]any{"keys": []any{"*_token"}, "values": []any{"v1"}})
+	if assert.NoError(t, err) {
+		sf := built.(*Redactor)
+		assert.True(t, sf.IsSecretKey("api_token"))
+		assert.Equal(t, RedactedValue, sf.Redact("v1"))
+	}
+}
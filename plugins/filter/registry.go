@@ -0,0 +1,68 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates the filter plugin by the options in config
+type Factory func(options map[string]any) (IFilter, error)
+
+var (
+	rmu      sync.RWMutex
+	registry = map[string]Factory{}
+)
+
+// Register registers the filter plugin by the name, the later registration replaces the former
+func Register(name string, factory Factory) {
+	rmu.Lock()
+	defer rmu.Unlock()
+	registry[name] = factory
+}
+
+// Build creates the registered filter plugin by the name
+func Build(name string, options map[string]any) (IFilter, error) {
+	rmu.RLock()
+	factory, ok := registry[name]
+	rmu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown filter plugin '%s'", name)
+	}
+
+	ft, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("filter plugin '%s': %w", name, err)
+	}
+	return ft, nil
+}
+
+// Registered returns the names of registered filter plugins in order
+func Registered() []string {
+	rmu.RLock()
+	defer rmu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
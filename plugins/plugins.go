@@ -16,3 +16,69 @@
 */
 
 package plugins
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
+)
+
+// Config enables the registered plugins by name, e.g.
+//
+//	callbacks:
+//	  - name: jsonl
+//	    options:
+//	      path: /var/log/bee/events.jsonl
+//	filters:
+//	  - name: redact
+type Config struct {
+	Callbacks []*PluginConfig `json:"callbacks,omitempty" yaml:"callbacks,omitempty"`
+	Filters   []*PluginConfig `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+// PluginConfig is the name and the options of plugin
+type PluginConfig struct {
+	Name    string         `json:"name" yaml:"name"`
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// LoadConfig reads Config from the yaml file
+func LoadConfig(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Wrapf(err, "parse '%s'", name)
+	}
+	return cfg, nil
+}
+
+// Build creates the enabled plugins in the order of Config
+func (cfg *Config) Build() ([]callback.ICallBack, []filter.IFilter, error) {
+	callbacks := make([]callback.ICallBack, 0, len(cfg.Callbacks))
+	for _, pc := range cfg.Callbacks {
+		cb, err := callback.Build(pc.Name, pc.Options)
+		if err != nil {
+			return nil, nil, err
+		}
+		callbacks = append(callbacks, cb)
+	}
+
+	filters := make([]filter.IFilter, 0, len(cfg.Filters))
+	for _, pc := range cfg.Filters {
+		ft, err := filter.Build(pc.Name, pc.Options)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, ft)
+	}
+
+	return callbacks, filters, nil
+}
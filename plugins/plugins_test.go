@@ -0,0 +1,67 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
+)
+
+func TestConfig_Build(t *testing.T) {
+	callback.Register("test-callback", func(options map[string]any) (callback.ICallBack, error) {
+		return &callback.BaseCallBack{}, nil
+	})
+	filter.Register("test-filter", func(options map[string]any) (filter.IFilter, error) {
+		return &filter.BaseFilter{}, nil
+	})
+
+	name := filepath.Join(t.TempDir(), "config.yml")
+	text := `
+callbacks:
+  - name: test-callback
+    options:
+      path: /tmp/events.jsonl
+filters:
+  - name: test-filter
+`
+	if err := os.WriteFile(name, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(name)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]any{"path": "/tmp/events.jsonl"}, cfg.Callbacks[0].Options)
+
+	callbacks, filters, err := cfg.Build()
+	if assert.NoError(t, err) {
+		assert.Len(t, callbacks, 1)
+		assert.Len(t, filters, 1)
+	}
+
+	cfg.Filters = append(cfg.Filters, &PluginConfig{Name: "unknown"})
+	_, _, err = cfg.Build()
+	assert.Error(t, err)
+}
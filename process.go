@@ -21,10 +21,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/hashicorp/go-multierror"
-	json "github.com/json-iterator/go"
 	"github.com/olive-io/bpmn/flow"
 	"github.com/olive-io/bpmn/flow_node/activity"
 	"github.com/olive-io/bpmn/flow_node/activity/script"
@@ -33,52 +34,317 @@ import (
 	"github.com/olive-io/bpmn/process/instance"
 	"github.com/olive-io/bpmn/schema"
 	"github.com/olive-io/bpmn/tracing"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/history"
 	"github.com/olive-io/bee/plugins/callback"
-	"github.com/olive-io/bee/plugins/filter"
 	"github.com/olive-io/bee/process"
 	"github.com/olive-io/bee/stats"
 )
 
-func (rt *Runtime) Play(ctx context.Context, pr *process.Process, opts ...RunOption) error {
-	definitions, dataObjects, properties, err := pr.Build()
+// BatchError reports the batch of hosts which stops the rollout of process
+type BatchError struct {
+	// Index is the index of batch, starts from 0
+	Index int
+	// Hosts are all hosts of the batch
+	Hosts []string
+	// Failed are the failed hosts of the batch
+	Failed []string
+	Err    error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d failed on %d/%d hosts [%s]: %v",
+		e.Index+1, len(e.Failed), len(e.Hosts), strings.Join(e.Failed, ","), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// RunReport is the report of Play
+type RunReport struct {
+	// RunId is the id of run in History
+	RunId string
+	// Process is the name of process
+	Process string
+	// Stats counts the task results of hosts in the process
+	Stats *bexecutor.AggregateStats
+	// StartAt and EndAt are the time of play
+	StartAt time.Time
+	EndAt   time.Time
+}
+
+// Failed returns true if any task failed or any host is unreachable
+func (r *RunReport) Failed() bool {
+	for _, host := range r.Stats.Hosts() {
+		if r.Stats.Get(bexecutor.Failures, host) > 0 || r.Stats.Get(bexecutor.Dark, host) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Play runs process.Process. The child processes which have serial run as
+// standalone segments, see process.Process Segments. The rollout stops with
+// *BatchError when the failed hosts of a batch exceed the max fail percentage.
+// The returned RunReport counts the task results of hosts, it is returned even if the play fails.
+// The progress of play is checkpointed by the RunId of report, see Resume.
+func (rt *Runtime) Play(ctx context.Context, pr *process.Process, opts ...RunOption) (*RunReport, error) {
+	startAt := time.Now()
+	report := &RunReport{
+		Process: pr.Name,
+		Stats:   bexecutor.NewStats(),
+		StartAt: startAt,
+	}
+	// the results are recorded in History
+	recorder := &historyCallBack{}
+	// the registered outputs are shared by the batches and segments of play
+	opts = append(opts[:len(opts):len(opts)], WithRunStats(report.Stats), WithRunCallback(recorder),
+		withRunRegistry(newRegistry()))
+
+	runOptions := newRunOptions()
+	for _, opt := range opts {
+		opt(runOptions)
+	}
+	cb := runOptions.Callback
+	if cb == nil {
+		cb = callback.NewCallBack()
+	}
+	report.RunId = runOptions.RunId
+	if report.RunId == "" {
+		report.RunId = history.NewRunId(startAt)
+	}
+
+	ckp, err := rt.newPlayCheckpoint(pr, report.RunId, runOptions)
 	if err != nil {
-		return err
+		return report, err
 	}
+	opts = append(opts, withRunCheckpoint(ckp))
+	// the unfinished run is in the history until the play ends
+	rt.recordPlay(pr, report, recorder, nil, runOptions)
 
-	return rt.RunBpmnProcess(ctx, definitions, dataObjects, properties, opts...)
+	cb.PlayOnStart(pr)
+	err = rt.playSegments(ctx, pr, opts...)
+	report.EndAt = time.Now()
+	cb.PlayOnEnd(pr, report.Stats)
+	rt.recordPlay(pr, report, recorder, err, runOptions)
+
+	return report, err
 }
 
-func (rt *Runtime) RunBpmnProcess(ctx context.Context, definitions *schema.Definitions, dataObjects, properties map[string]string, opts ...RunOption) error {
+// Resume resumes the play of run from its checkpoint. The tasks and hosts which
+// succeeded are skipped and their registered results are restored, only the failed
+// and pending ones run again. The extra vars, limit, check and diff of the play are
+// restored, opts are applied after them. The secret extra vars are masked in the
+// checkpoint, they have to be given again by WithRunExtraVars. The resumed play has a new RunId.
+func (rt *Runtime) Resume(ctx context.Context, runId string, opts ...RunOption) (*RunReport, error) {
+	cp, err := rt.history.GetCheckpoint(runId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get checkpoint of run '%s'", runId)
+	}
 
-	lg := rt.Logger()
+	pr := &process.Process{}
+	if err = yaml.Unmarshal([]byte(cp.Process), pr); err != nil {
+		return nil, errors.Wrapf(err, "decode process of run '%s'", runId)
+	}
+
+	ropts := []RunOption{
+		WithRunExtraVars(cp.ExtraVars),
+		WithRunCheck(cp.Check),
+		WithRunDiff(cp.Diff),
+	}
+	if len(cp.Limit) > 0 {
+		ropts = append(ropts, WithRunLimit(cp.Limit...))
+	}
+	ropts = append(ropts, opts...)
+	ropts = append(ropts, withRunResume(cp))
+
+	runOptions := newRunOptions()
+	for _, opt := range ropts {
+		opt(runOptions)
+	}
+	if masked := maskedVars(runOptions.ExtraVars); len(masked) > 0 {
+		return nil, errors.Newf("extra vars %s of run '%s' are masked, give them again to resume",
+			strings.Join(masked, ", "), runId)
+	}
+	masked, err := maskedProcessVars(cp.Process)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode process of run '%s'", runId)
+	}
+	// the extra vars take precedence over the masked vars of process
+	masked = lo.Filter[string](masked, func(name string, _ int) bool {
+		_, ok := runOptions.ExtraVars[name]
+		return !ok
+	})
+	if len(masked) > 0 {
+		return nil, errors.Newf("vars %s of the process of run '%s' are masked, give them again by extra vars to resume",
+			strings.Join(masked, ", "), runId)
+	}
+
+	return rt.Play(ctx, pr, ropts...)
+}
+
+// newPlayCheckpoint saves the checkpoint of play before it starts, the ids of
+// tasks are assigned to rebuild the same process when it resumes.
+func (rt *Runtime) newPlayCheckpoint(pr *process.Process, runId string, options *RunOptions) (*checkpointer, error) {
+	pr.AssignIds()
+	// the secrets of vars are masked, they are given again by extra vars when it resumes
+	data, err := redactProcess(options.Filter, pr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode process '%s'", pr.Name)
+	}
+
+	cp := history.NewCheckpoint(runId)
+	if prev := options.resume; prev != nil {
+		cp = prev.Resume(runId)
+	}
+	cp.Name = pr.Name
+	cp.Process = data
+	cp.ExtraVars = redactVars(options.Filter, options.ExtraVars)
+	cp.Limit = options.Limit
+	cp.Check = options.Check || rt.opts.check
+	cp.Diff = options.Diff
+
+	ckp := newCheckpointer(rt, cp, options.resume)
+	ckp.save()
+	return ckp, nil
+}
+
+// playSegments runs the segments of process in order
+func (rt *Runtime) playSegments(ctx context.Context, pr *process.Process, opts ...RunOption) error {
+	var tolerated error
+	for _, segment := range pr.Segments() {
+		err := rt.playSerial(ctx, segment, opts...)
+		if err == nil {
+			continue
+		}
+
+		var be *BatchError
+		if len(segment.Serial) == 0 || errors.As(err, &be) {
+			return err
+		}
+		tolerated = multierror.Append(tolerated, err)
+	}
+
+	return tolerated
+}
+
+// playSerial runs the process batch by batch
+func (rt *Runtime) playSerial(ctx context.Context, pr *process.Process, opts ...RunOption) error {
+	definitions, dataObjects, properties, err := pr.Build()
+	if err != nil {
+		return err
+	}
+
+	if len(pr.Serial) == 0 {
+		return rt.RunBpmnProcess(ctx, definitions, dataObjects, properties, opts...)
+	}
 
 	runOptions := newRunOptions()
 	for _, opt := range opts {
 		opt(runOptions)
 	}
 
-	cb := callback.NewCallBack()
-	if runOptions.Callback != nil {
-		cb = runOptions.Callback
+	var patterns []string
+	if v, ok := properties["hosts"]; ok && v != "" {
+		patterns = strings.Split(v, ",")
+	}
+	hosts, err := rt.resolveHosts(patterns, runOptions.Limit)
+	if err != nil {
+		return err
+	}
+	batches, err := process.SplitBatches(hosts, pr.Serial)
+	if err != nil {
+		return err
 	}
 
-	ft := filter.NewFilter()
-	if runOptions.Filter != nil {
-		ft = runOptions.Filter
+	lg := rt.Logger()
+	var tolerated error
+	for i, batch := range batches {
+		cb := newBatchCallBack()
+		bopts := append(opts[:len(opts):len(opts)], WithRunCallback(cb), withRunBatch(batch))
+
+		lg.Info("run process batch",
+			zap.String("process", pr.Name),
+			zap.Int("batch", i+1),
+			zap.Strings("hosts", batch))
+		err = rt.RunBpmnProcess(ctx, definitions, dataObjects, properties, bopts...)
+		if err == nil {
+			continue
+		}
+
+		failed := cb.failedHosts(batch)
+		if len(failed) == 0 {
+			// the error isn't about any host, the batch fails entirely
+			failed = batch
+		}
+		if pr.BatchFailed(len(failed), len(batch)) {
+			return &BatchError{Index: i, Hosts: batch, Failed: failed, Err: err}
+		}
+
+		lg.Warn("tolerate failed hosts of batch",
+			zap.String("process", pr.Name),
+			zap.Int("batch", i+1),
+			zap.Strings("failed", failed))
+		tolerated = multierror.Append(tolerated, err)
 	}
 
-	var sources []string
-	if v, ok := properties["hosts"]; ok {
-		sources = strings.Split(v, ",")
+	return tolerated
+}
+
+// batchCallBack records the failed hosts of batch
+type batchCallBack struct {
+	callback.BaseCallBack
+
+	mu     sync.Mutex
+	failed map[string]struct{}
+}
+
+func newBatchCallBack() *batchCallBack {
+	return &batchCallBack{
+		failed: map[string]struct{}{},
 	}
+}
 
-	if len(sources) == 0 {
-		return fmt.Errorf("missing sources")
+func (cb *batchCallBack) RunnerOnUnreachable(result *stats.TaskResult) {
+	cb.fail(result.Host)
+}
+
+func (cb *batchCallBack) RunnerOkFailed(result *stats.TaskResult) {
+	cb.fail(result.Host)
+}
+
+func (cb *batchCallBack) fail(host string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failed[host] = struct{}{}
+}
+
+// failedHosts returns the failed hosts in the order of hosts
+func (cb *batchCallBack) failedHosts(hosts []string) []string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return lo.Filter[string](hosts, func(host string, _ int) bool {
+		_, ok := cb.failed[host]
+		return ok
+	})
+}
+
+func (rt *Runtime) RunBpmnProcess(ctx context.Context, definitions *schema.Definitions, dataObjects, properties map[string]string, opts ...RunOption) error {
+
+	lg := rt.Logger()
+
+	runOptions := newRunOptions()
+	for _, opt := range opts {
+		opt(runOptions)
 	}
 
-	if err := rt.inventory.AddSources(sources...); err != nil {
+	r, err := rt.newRunner(properties, opts...)
+	if err != nil {
 		return err
 	}
 
@@ -114,8 +380,6 @@ func (rt *Runtime) RunBpmnProcess(ctx context.Context, definitions *schema.Defin
 	}
 	defer ins.Tracer.Unsubscribe(traces)
 
-	runTasks := make([]process.ITask, 0)
-
 LOOP:
 	for {
 		var trace tracing.ITrace
@@ -136,100 +400,17 @@ LOOP:
 			act := tt.GetActivity()
 			id, _ := act.Element().Id()
 
-			tProps, tHeaders := ft.OnPreTaskProps(*id, tt.GetProperties(), tt.GetHeaders())
+			tProps, tHeaders := r.ft.OnPreTaskProps(*id, tt.GetProperties(), tt.GetHeaders())
 			rspProperties := map[string]any{}
 
 			var aErr error
 			switch act.(type) {
 			case *service.Node:
-
 				sv := process.DecodeServiceTask(tProps, tHeaders)
-				runTasks = append(runTasks, sv)
-				hosts := sv.Hosts
-				if len(hosts) == 0 {
-					hosts = sources
-				}
-
-				if caller := rt.opts.caller; caller != nil {
-					for _, host := range hosts {
-						result := &stats.TaskResult{
-							Host: host,
-						}
-
-						ropts := append(opts, WithMetadata(tHeaders))
-						in, _ := json.Marshal(sv.Args)
-						data, err := caller(ctx, host, sv.Action, in, ropts...)
-						if err != nil {
-							aErr = multierror.Append(aErr, err)
-							result.ErrMsg = err.Error()
-							cb.RunnerOkFailed(result)
-							continue
-						}
-
-						stdout := map[string]any{}
-						if err = json.Unmarshal(data, &stdout); err != nil {
-							aErr = multierror.Append(aErr, err)
-							result.ErrMsg = err.Error()
-							cb.RunnerOkFailed(result)
-							continue
-						}
-
-						stdout = ft.OnPostTaskStdout(*id, stdout)
-						result.Stdout = stdout
-						for key, value := range stdout {
-							rspProperties[key] = value
-						}
-
-						cb.RunnerOnOk(result)
-					}
-				}
-
+				rspProperties, aErr = r.runService(ctx, *id, sv, tHeaders)
 			case *script.Node:
-
 				task := process.DecodeScriptTask(tProps, tHeaders)
-				runTasks = append(runTasks, task)
-				hosts := task.Hosts
-				if len(hosts) == 0 {
-					hosts = sources
-				}
-
-				args := make([]string, 0)
-				args = append(args, task.Action)
-				for name, arg := range task.Args {
-					value, _ := json.Marshal(arg)
-					args = append(args, name+"="+strings.ReplaceAll(string(value), "\"", ""))
-				}
-				shell := strings.Join(args, " ")
-				for _, host := range hosts {
-					result := &stats.TaskResult{
-						Host: host,
-					}
-
-					ropts := append(opts, WithMetadata(tHeaders))
-					data, err := rt.Execute(ctx, host, shell, ropts...)
-					if err != nil {
-						aErr = multierror.Append(aErr, err)
-						result.ErrMsg = err.Error()
-						cb.RunnerOkFailed(result)
-						continue
-					}
-
-					stdout := map[string]any{}
-					if err = json.Unmarshal(data, &stdout); err != nil {
-						aErr = multierror.Append(aErr, err)
-						result.ErrMsg = err.Error()
-						cb.RunnerOkFailed(result)
-						continue
-					}
-
-					stdout = ft.OnPostTaskStdout(*id, stdout)
-					result.Stdout = stdout
-					for key, value := range stdout {
-						rspProperties[key] = value
-					}
-
-					cb.RunnerOnOk(result)
-				}
+				rspProperties, aErr = r.runScript(ctx, *id, task, tHeaders)
 			}
 
 			actOpts := make([]activity.DoOption, 0)
@@ -250,7 +431,8 @@ LOOP:
 	}
 	ins.WaitUntilComplete(ctx)
 
-	if err != nil {
+	runTasks := r.tasks
+	if err != nil || r.tolerated != nil {
 		for i := len(runTasks) - 1; i >= 0; i-- {
 			task := runTasks[i]
 
@@ -260,7 +442,8 @@ LOOP:
 			}
 
 			fields := make([]zap.Field, 0)
-			if named, ok := task.(process.INamedTask); ok {
+			named, ok := task.(process.INamedTask)
+			if ok {
 				fields = append(fields,
 					zap.String("name", named.GetName()),
 					zap.String("id", named.GetId()))
@@ -272,15 +455,15 @@ LOOP:
 				continue
 			}
 
-			hosts := caught.GetHosts()
-			if len(hosts) == 0 {
-				hosts = sources
-			}
+			hosts, _ := r.taskHosts(caught.GetHosts())
 
 			fields = append(fields, zap.Stringer("handler", catch))
 			lg.Info("handle task catch", fields...)
+			r.cb.HandlerOnCatch(named, catch, hosts)
 
-			_ = rt.handle(ctx, hosts, catch)
+			if e1 := r.handle(ctx, hosts, catch, r.taskOptions(task)...); e1 == nil {
+				r.rescue(hosts)
+			}
 		}
 	}
 
@@ -293,7 +476,8 @@ LOOP:
 		}
 
 		fields := make([]zap.Field, 0)
-		if named, ok := task.(process.INamedTask); ok {
+		named, ok := task.(process.INamedTask)
+		if ok {
 			fields = append(fields,
 				zap.String("name", named.GetName()),
 				zap.String("id", named.GetId()))
@@ -305,61 +489,91 @@ LOOP:
 			continue
 		}
 
-		hosts := caught.GetHosts()
-		if len(hosts) == 0 {
-			hosts = sources
-		}
+		hosts, _ := r.taskHosts(caught.GetHosts())
 
 		fields = append(fields, zap.Stringer("handler", finish))
 		lg.Info("handle service finish", fields...)
+		r.cb.HandlerOnFinish(named, finish, hosts)
+
+		_ = r.handle(ctx, hosts, finish, r.taskOptions(task)...)
+	}
 
-		_ = rt.handle(ctx, hosts, finish)
+	if err == nil {
+		// the notified handlers run at the end of process
+		err = r.flushHandlers(ctx)
+	}
+	if r.tolerated != nil {
+		// the batch failed on some hosts, see Process.BatchFailed
+		err = multierror.Append(r.tolerated, err)
 	}
 
 	return err
 }
 
-func (rt *Runtime) handle(ctx context.Context, hosts []string, handler *process.Handler, opts ...RunOption) error {
-	switch handler.Kind {
-	case process.ServiceKey:
-		caller := rt.opts.caller
-		if caller == nil {
-			return nil
-		}
+// privilegeOptions returns the options of execution user of task, the handlers of task run as the same user
+func privilegeOptions(task process.ITask) []RunOption {
+	t, ok := task.(*process.Task)
+	if !ok {
+		return nil
+	}
+	return []RunOption{WithRunUser(t.RemoteUser), WithRunBecome(t.Sudo, t.SudoUser)}
+}
 
-		for _, host := range hosts {
-			ropts := append(opts)
-			in, _ := json.Marshal(handler.Args)
-			data, err := caller(ctx, host, handler.Action, in, ropts...)
-			if err != nil {
-				return err
-			}
+// privilegeKey returns the key of execution user of task, the tasks of the same key run as the same user
+func privilegeKey(task process.ITask) string {
+	t, ok := task.(*process.Task)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s/%t/%s", t.RemoteUser, t.Sudo, t.SudoUser)
+}
 
-			stdout := map[string]any{}
-			if err = json.Unmarshal(data, &stdout); err != nil {
-			}
-		}
+// runOnHosts calls fn on all hosts concurrently, at most forks hosts at the same time.
+// The forks is bounded by the parallel of Runtime, the outputs and errors keep the order of hosts.
+func (rt *Runtime) runOnHosts(ctx context.Context, hosts []string, forks int, fn func(ctx context.Context, host string) ([]byte, error)) ([][]byte, []error) {
+	parallel := rt.opts.parallel
+	if forks <= 0 || forks > parallel {
+		forks = parallel
+	}
 
-	case process.TaskKey:
-		args := make([]string, 0)
-		args = append(args, handler.Action)
-		for name, arg := range handler.Args {
-			value, _ := json.Marshal(arg)
-			args = append(args, name+"="+strings.ReplaceAll(string(value), "\"", ""))
-		}
-		shell := strings.Join(args, " ")
-		for _, host := range hosts {
-			ropts := append(opts)
-			data, err := rt.Execute(ctx, host, shell, ropts...)
-			if err != nil {
-				return err
-			}
+	outs := make([][]byte, len(hosts))
+	errs := make([]error, len(hosts))
 
-			stdout := map[string]any{}
-			if err = json.Unmarshal(data, &stdout); err != nil {
-			}
+	limit := make(chan struct{}, forks)
+	var wg sync.WaitGroup
+	for i := range hosts {
+		select {
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		case limit <- struct{}{}:
 		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			outs[i], errs[i] = fn(ctx, hosts[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return outs, errs
+}
+
+// resolveHosts expands the host patterns by inventory, only keeps the hosts in limit if it is not empty
+func (rt *Runtime) resolveHosts(patterns, limit []string) ([]string, error) {
+	hosts, err := rt.inventory.ResolveHosts(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if len(limit) == 0 {
+		return hosts, nil
 	}
 
-	return nil
+	return lo.Filter[string](hosts, func(host string, _ int) bool {
+		return lo.Contains[string](limit, host)
+	}), nil
 }
@@ -57,6 +57,17 @@ func (b *Builder) SetVar(name string, value any) *Builder {
 	return b
 }
 
+// SetSerial sets the batches of hosts, each of them is a count or a percentage like "25%"
+func (b *Builder) SetSerial(serial ...string) *Builder {
+	b.p.Serial = serial
+	return b
+}
+
+func (b *Builder) SetMaxFailPercentage(percentage int) *Builder {
+	b.p.MaxFailPercentage = &percentage
+	return b
+}
+
 func (b *Builder) SetHandlers(handlers ...*Handler) *Builder {
 	b.p.Handlers = append(b.p.Handlers, handlers...)
 	return b
@@ -114,6 +125,17 @@ func (b *ChildProcessBuilder) SetVar(name string, value any) *ChildProcessBuilde
 	return b
 }
 
+// SetSerial sets the batches of hosts, each of them is a count or a percentage like "25%"
+func (b *ChildProcessBuilder) SetSerial(serial ...string) *ChildProcessBuilder {
+	b.p.Serial = serial
+	return b
+}
+
+func (b *ChildProcessBuilder) SetMaxFailPercentage(percentage int) *ChildProcessBuilder {
+	b.p.MaxFailPercentage = &percentage
+	return b
+}
+
 func (b *ChildProcessBuilder) SetHandlers(handlers ...*Handler) *ChildProcessBuilder {
 	b.p.Handlers = append(b.p.Handlers, handlers...)
 	return b
@@ -124,6 +146,11 @@ func (b *ChildProcessBuilder) SetTasks(tasks ...ITask) *ChildProcessBuilder {
 	return b
 }
 
+func (b *ChildProcessBuilder) SetWhen(when string) *ChildProcessBuilder {
+	b.p.When = when
+	return b
+}
+
 func (b *ChildProcessBuilder) Build() *ChildProcess {
 	return b.p
 }
@@ -162,6 +189,11 @@ func (b *TaskBuilder) SetHosts(hosts ...string) *TaskBuilder {
 	return b
 }
 
+func (b *TaskBuilder) SetForks(forks int) *TaskBuilder {
+	b.p.Forks = forks
+	return b
+}
+
 func (b *TaskBuilder) SetVar(name string, value any) *TaskBuilder {
 	if b.p.Vars == nil {
 		b.p.Vars = map[string]any{}
@@ -191,6 +223,35 @@ func (b *TaskBuilder) SetNotify(notify ...string) *TaskBuilder {
 	return b
 }
 
+func (b *TaskBuilder) SetWhen(when string) *TaskBuilder {
+	b.p.When = when
+	return b
+}
+
+func (b *TaskBuilder) SetRegister(name string) *TaskBuilder {
+	b.p.Register = name
+	return b
+}
+
+// SetRetries reruns the action at most retries times on the failed host, waits delay seconds before each rerun
+func (b *TaskBuilder) SetRetries(retries, delay int) *TaskBuilder {
+	b.p.Retries = retries
+	b.p.Delay = delay
+	return b
+}
+
+// SetNoLog hides the output of action from the logs and callbacks
+func (b *TaskBuilder) SetNoLog(noLog bool) *TaskBuilder {
+	b.p.NoLog = noLog
+	return b
+}
+
+func (b *TaskBuilder) SetLoop(loop any, loopVar string) *TaskBuilder {
+	b.p.Loop = loop
+	b.p.LoopVar = loopVar
+	return b
+}
+
 func (b *TaskBuilder) Build() *Task {
 	return b.p
 }
@@ -230,6 +291,11 @@ func (b *ServiceBuilder) SetHosts(hosts ...string) *ServiceBuilder {
 	return b
 }
 
+func (b *ServiceBuilder) SetForks(forks int) *ServiceBuilder {
+	b.p.Forks = forks
+	return b
+}
+
 func (b *ServiceBuilder) SetVar(name string, value any) *ServiceBuilder {
 	if b.p.Vars == nil {
 		b.p.Vars = map[string]any{}
@@ -259,6 +325,35 @@ func (b *ServiceBuilder) SetNotify(notify ...string) *ServiceBuilder {
 	return b
 }
 
+func (b *ServiceBuilder) SetWhen(when string) *ServiceBuilder {
+	b.p.When = when
+	return b
+}
+
+func (b *ServiceBuilder) SetRegister(name string) *ServiceBuilder {
+	b.p.Register = name
+	return b
+}
+
+// SetRetries reruns the action at most retries times on the failed host, waits delay seconds before each rerun
+func (b *ServiceBuilder) SetRetries(retries, delay int) *ServiceBuilder {
+	b.p.Retries = retries
+	b.p.Delay = delay
+	return b
+}
+
+// SetNoLog hides the output of action from the logs and callbacks
+func (b *ServiceBuilder) SetNoLog(noLog bool) *ServiceBuilder {
+	b.p.NoLog = noLog
+	return b
+}
+
+func (b *ServiceBuilder) SetLoop(loop any, loopVar string) *ServiceBuilder {
+	b.p.Loop = loop
+	b.p.LoopVar = loopVar
+	return b
+}
+
 func (b *ServiceBuilder) Build() *Service {
 	return b.p
 }
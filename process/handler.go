@@ -21,6 +21,10 @@ import (
 	"fmt"
 )
 
+// FlushHandlersAction is the action of Task which runs the notified handlers
+// immediately, instead of at the end of process.
+const FlushHandlersAction = "flush_handlers"
+
 type Handler struct {
 	Name string `json:"name,omitempty" yaml:"name,omitempty"`
 	Id   string `json:"id,omitempty" yaml:"id,omitempty"`
@@ -69,7 +73,14 @@ func (h *Handler) fromKV(kv YamlKV) (err error) {
 			continue
 		}
 
-		if key == "" {
+		if key == "args" {
+			if ykv, ok := value.(YamlKV); ok {
+				h.Args = ykv
+			}
+			continue
+		}
+
+		if h.Action == "" {
 			h.Action = key
 		}
 		if vs, ok := value.(string); ok {
@@ -85,3 +96,21 @@ func (h *Handler) fromKV(kv YamlKV) (err error) {
 func (h *Handler) String() string {
 	return fmt.Sprintf(`{"%s": %s}`, h.Name, h.Action)
 }
+
+// collectHandlers returns the handlers of process and its child processes in the order of definition,
+// the handler without id gets a new one.
+func collectHandlers(handlers []*Handler, tasks []ITask) []*Handler {
+	out := make([]*Handler, 0, len(handlers))
+	for _, handler := range handlers {
+		if handler.Id == "" {
+			handler.Id = newSnoId()
+		}
+		out = append(out, handler)
+	}
+	for _, task := range tasks {
+		if cp, ok := task.(*ChildProcess); ok {
+			out = append(out, collectHandlers(cp.Handlers, cp.Tasks)...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLoopVar is the default name of the loop variable
+const DefaultLoopVar = "item"
+
+// parseLoop normalizes the loop from yaml, it is a list of items or an expression resolving to a list
+func parseLoop(value any) (any, error) {
+	switch tt := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.TrimSpace(tt), nil
+	case []any:
+		items := make([]any, len(tt))
+		for i, item := range tt {
+			if ykv, ok := item.(YamlKV); ok {
+				item = map[string]any(ykv)
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("invalid loop '%v'", value)
+	}
+}
+
+// GetLoopVar returns the name of loop variable
+func (t *Task) GetLoopVar() string {
+	if t.LoopVar == "" {
+		return DefaultLoopVar
+	}
+	return t.LoopVar
+}
+
+// GetLoopVar returns the name of loop variable
+func (s *Service) GetLoopVar() string {
+	if s.LoopVar == "" {
+		return DefaultLoopVar
+	}
+	return s.LoopVar
+}
@@ -0,0 +1,61 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProcess_UnmarshalLoop(t *testing.T) {
+	text := `
+name: loop
+hosts: webservers
+tasks:
+- name: create users
+  action: user
+  args:
+    name: "{{ user.name }}"
+  loop:
+  - name: alice
+  - name: bob
+  loop_var: user
+- name: install packages
+  kind: service
+  action: install
+  loop: packages`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) || !assert.Len(t, pr.Tasks, 2) {
+		return
+	}
+
+	task := pr.Tasks[0].(*Task)
+	assert.Equal(t, []any{map[string]any{"name": "alice"}, map[string]any{"name": "bob"}}, task.Loop)
+	assert.Equal(t, "user", task.GetLoopVar())
+
+	sv := pr.Tasks[1].(*Service)
+	assert.Equal(t, "packages", sv.Loop)
+	assert.Equal(t, DefaultLoopVar, sv.GetLoopVar())
+
+	err = yaml.Unmarshal([]byte("tasks: [{action: ping, loop: 1}]"), &Process{})
+	assert.Error(t, err)
+}
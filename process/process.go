@@ -40,9 +40,19 @@ type Process struct {
 	Sudo     bool   `json:"sudo,omitempty" yaml:"sudo,omitempty"`
 	SudoUser string `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
 
+	// Serial runs the process over batches of hosts, see SplitBatches. The host which fails a
+	// task in a batch skips the later tasks, the other hosts of batch keep running them.
+	Serial []string `json:"serial,omitempty" yaml:"serial,omitempty"`
+	// MaxFailPercentage aborts the rollout when the percentage of failed hosts in a batch exceeds it,
+	// the rollout is aborted only when all hosts of a batch fail if it isn't set. See BatchFailed
+	MaxFailPercentage *int `json:"max_fail_percentage,omitempty" yaml:"max_fail_percentage,omitempty"`
+
 	Tasks []ITask `json:"tasks,omitempty" yaml:"tasks,omitempty"`
 
 	Handlers []*Handler `json:"handlers,omitempty" yaml:"handlers,omitempty"`
+
+	// when are the conditions inherited from the parents, see Segments
+	when []string
 }
 
 func (p *Process) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
@@ -97,6 +107,7 @@ func (p *Process) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
 			if err != nil {
 				return
 			}
+			continue
 		}
 
 		if key == "sudo" {
@@ -112,6 +123,25 @@ func (p *Process) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
 			if err != nil {
 				return
 			}
+			continue
+		}
+
+		if key == "serial" {
+			p.Serial, err = parseSerial(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		if key == "max_fail_percentage" {
+			var percentage int
+			_, err = kv.Apply("max_fail_percentage", &percentage)
+			if err != nil {
+				return
+			}
+			p.MaxFailPercentage = &percentage
+			continue
 		}
 
 		if key == "tasks" {
@@ -172,6 +202,40 @@ func (p *Process) ToBuilder() *Builder {
 	return &Builder{p: p}
 }
 
+// AssignIds sets the ids of process, tasks and handlers which don't have one,
+// the ids keep the same when the process is built again.
+func (p *Process) AssignIds() {
+	if p.Id == "" {
+		p.Id = newSnoId()
+	}
+	assignIds(p.Tasks, p.Handlers)
+}
+
+func assignIds(tasks []ITask, handlers []*Handler) {
+	for _, handler := range handlers {
+		if handler.Id == "" {
+			handler.Id = newSnoId()
+		}
+	}
+	for _, task := range tasks {
+		switch act := task.(type) {
+		case *ChildProcess:
+			if act.Id == "" {
+				act.Id = newSnoId()
+			}
+			assignIds(act.Tasks, act.Handlers)
+		case *Task:
+			if act.Id == "" {
+				act.Id = newSnoId()
+			}
+		case *Service:
+			if act.Id == "" {
+				act.Id = newSnoId()
+			}
+		}
+	}
+}
+
 func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]string, error) {
 	pb := builder.NewProcessDefinitionsBuilder(p.Name)
 	if p.Id == "" {
@@ -183,6 +247,7 @@ func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]st
 	properties := map[string]string{}
 
 	hosts := p.Hosts
+	pv := scope{when: p.when}.inherit(p.RemoteUser, p.Sudo, p.SudoUser)
 	if p.Sudo {
 		properties["sudo"] = ""
 	}
@@ -197,7 +262,7 @@ func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]st
 		st := p.Tasks[idx]
 		switch act := st.(type) {
 		case *ChildProcess:
-			out, ds, props, err := buildChildProcess(act)
+			out, ds, props, err := buildChildProcess(act, pv)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -215,7 +280,7 @@ func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]st
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeScriptTask(act)
+			props, headers := EncodeScriptTask(pv.applyTask(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -231,7 +296,7 @@ func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]st
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeServiceTask(act)
+			props, headers := EncodeServiceTask(pv.applyService(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -249,6 +314,12 @@ func (p *Process) Build() (*schema.Definitions, map[string]string, map[string]st
 		properties[key] = property
 	}
 	properties["hosts"] = strings.Join(lo.Uniq[string](hosts), ",")
+	if handlers := collectHandlers(p.Handlers, p.Tasks); len(handlers) > 0 {
+		properties["handlers"] = EncodeHandlers(handlers)
+	}
+	if len(p.Vars) > 0 {
+		properties["vars"] = EncodeVars(p.Vars)
+	}
 
 	definitions, err := pb.ToDefinitions()
 	if err != nil {
@@ -269,6 +340,7 @@ func (p *Process) SubBuild() (*schema.Definitions, map[string]string, map[string
 	properties := map[string]string{}
 
 	hosts := p.Hosts
+	pv := scope{when: p.when}.inherit(p.RemoteUser, p.Sudo, p.SudoUser)
 	if p.Sudo {
 		properties["sudo"] = ""
 	}
@@ -283,7 +355,7 @@ func (p *Process) SubBuild() (*schema.Definitions, map[string]string, map[string
 		st := p.Tasks[idx]
 		switch act := st.(type) {
 		case *ChildProcess:
-			out, ds, props, err := buildChildProcess(act)
+			out, ds, props, err := buildChildProcess(act, pv)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -301,7 +373,7 @@ func (p *Process) SubBuild() (*schema.Definitions, map[string]string, map[string
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeScriptTask(act)
+			props, headers := EncodeScriptTask(pv.applyTask(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -317,7 +389,7 @@ func (p *Process) SubBuild() (*schema.Definitions, map[string]string, map[string
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeServiceTask(act)
+			props, headers := EncodeServiceTask(pv.applyService(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -335,13 +407,19 @@ func (p *Process) SubBuild() (*schema.Definitions, map[string]string, map[string
 		properties[key] = property
 	}
 	properties["hosts"] = strings.Join(lo.Uniq[string](hosts), ",")
+	if handlers := collectHandlers(p.Handlers, p.Tasks); len(handlers) > 0 {
+		properties["handlers"] = EncodeHandlers(handlers)
+	}
+	if len(p.Vars) > 0 {
+		properties["vars"] = EncodeVars(p.Vars)
+	}
 
 	definitions := pb.ToDefinitions()
 
 	return definitions, dataObjects, properties, nil
 }
 
-func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string]string, map[string]string, error) {
+func buildChildProcess(pr *ChildProcess, parent scope) (*builder.SubProcessBuilder, map[string]string, map[string]string, error) {
 	pb := builder.NewSubProcessDefinitionsBuilder(pr.Name)
 	if pr.Id == "" {
 		pr.Id = newSnoId()
@@ -351,6 +429,7 @@ func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string
 	dataObjects := map[string]string{}
 	properties := map[string]string{}
 	hosts := pr.Hosts
+	pv := parent.child(pr)
 
 	pb.Start()
 
@@ -358,7 +437,7 @@ func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string
 	for idx := range pr.Tasks {
 		st := pr.Tasks[idx]
 		if act, ok := st.(*ChildProcess); ok {
-			out, _, props, err := buildChildProcess(act)
+			out, _, props, err := buildChildProcess(act, pv)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -373,7 +452,7 @@ func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeScriptTask(act)
+			props, headers := EncodeScriptTask(pv.applyTask(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -390,7 +469,7 @@ func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string
 				act.Id = newSnoId()
 			}
 			sb.SetId(act.Id)
-			props, headers := EncodeServiceTask(act)
+			props, headers := EncodeServiceTask(pv.applyService(act))
 			for key, value := range props {
 				sb.SetProperty(key, value)
 			}
@@ -407,3 +486,73 @@ func buildChildProcess(pr *ChildProcess) (*builder.SubProcessBuilder, map[string
 	pb.End()
 	return pb.Out(), dataObjects, properties, nil
 }
+
+// scope is the settings which the tasks inherit from the processes
+type scope struct {
+	remoteUser string
+	sudo       bool
+	sudoUser   string
+	// when are the conditions of processes, the tasks run only when all of them are true
+	when []string
+	// vars are the variables of child processes
+	vars map[string]any
+}
+
+// inherit returns the scope of child, the settings of child take precedence
+func (s scope) inherit(remoteUser string, sudo bool, sudoUser string) scope {
+	if remoteUser != "" {
+		s.remoteUser = remoteUser
+	}
+	s.sudo = s.sudo || sudo
+	if sudoUser != "" {
+		s.sudoUser = sudoUser
+	}
+	return s
+}
+
+// child returns the scope of ChildProcess
+func (s scope) child(cp *ChildProcess) scope {
+	out := s.inherit(cp.RemoteUser, cp.Sudo, cp.SudoUser)
+	if cp.When != "" {
+		out.when = append(s.when[:len(s.when):len(s.when)], cp.When)
+	}
+	if len(cp.Vars) > 0 {
+		out.vars = mergeVars(s.vars, cp.Vars)
+	}
+	return out
+}
+
+// applyTask returns a copy of Task which inherits the scope
+func (s scope) applyTask(task *Task) *Task {
+	pv := s.inherit(task.RemoteUser, task.Sudo, task.SudoUser)
+	out := *task
+	out.RemoteUser = pv.remoteUser
+	out.Sudo = pv.sudo
+	out.SudoUser = pv.sudoUser
+	out.When = joinWhen(append(s.when[:len(s.when):len(s.when)], task.When)...)
+	if len(s.vars) > 0 {
+		out.Vars = mergeVars(s.vars, task.Vars)
+	}
+	return &out
+}
+
+// applyService returns a copy of Service which inherits the scope
+func (s scope) applyService(sv *Service) *Service {
+	out := *sv
+	out.When = joinWhen(append(s.when[:len(s.when):len(s.when)], sv.When)...)
+	if len(s.vars) > 0 {
+		out.Vars = mergeVars(s.vars, sv.Vars)
+	}
+	return &out
+}
+
+// mergeVars returns a new map of variables, the latter take precedence
+func mergeVars(vars ...map[string]any) map[string]any {
+	out := map[string]any{}
+	for _, item := range vars {
+		for key, value := range item {
+			out[key] = value
+		}
+	}
+	return out
+}
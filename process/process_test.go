@@ -20,6 +20,7 @@ package process
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
 
@@ -81,3 +82,161 @@ handlers:
 
 	t.Log(string(data))
 }
+
+func TestProcess_BuildPrivilege(t *testing.T) {
+	task := NewTaskBuilder().Named("t1", "", "").Build()
+	child := NewChildProcessBuilder().
+		Named("c1", "", "").
+		SetTasks(task).
+		Build()
+	child.SudoUser = "admin"
+	pr := NewProcessBuilder().SetHosts("web").SetTasks(child).Build()
+	pr.RemoteUser = "deploy"
+	pr.Sudo = true
+
+	pv := scope{}.inherit(pr.RemoteUser, pr.Sudo, pr.SudoUser)
+	cpv := pv.inherit(child.RemoteUser, child.Sudo, child.SudoUser)
+	out := cpv.applyTask(task)
+	assert.Equal(t, "deploy", out.RemoteUser)
+	assert.True(t, out.Sudo)
+	assert.Equal(t, "admin", out.SudoUser)
+	// the task itself isn't changed
+	assert.Equal(t, "", task.RemoteUser)
+
+	task.RemoteUser = "root"
+	assert.Equal(t, "root", cpv.applyTask(task).RemoteUser)
+
+	_, _, _, err := pr.Build()
+	assert.NoError(t, err)
+}
+
+func TestProcess_BuildHandlers(t *testing.T) {
+	child := NewChildProcessBuilder().
+		Named("c1", "", "").
+		SetHandlers(NewHandlerBuilder().Named("h2", "reload", "").Build()).
+		SetTasks(NewTaskBuilder().Named("t2", "", "").SetNotify("reload").Build()).
+		Build()
+	pr := NewProcessBuilder().
+		SetHosts("web").
+		SetHandlers(NewHandlerBuilder().Named("", "restart", "").Build()).
+		SetTasks(NewTaskBuilder().Named("t1", "", "").SetNotify("restart").Build(), child).
+		Build()
+
+	_, _, properties, err := pr.Build()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	handlers, err := DecodeHandlers(properties["handlers"])
+	if !assert.NoError(t, err) || !assert.Len(t, handlers, 2) {
+		return
+	}
+	assert.Equal(t, "restart", handlers[0].Name)
+	assert.NotEmpty(t, handlers[0].Id)
+	assert.Equal(t, "h2", handlers[1].Id)
+
+	handlers, err = DecodeHandlers("")
+	assert.NoError(t, err)
+	assert.Empty(t, handlers)
+}
+
+func TestProcess_UnmarshalRetries(t *testing.T) {
+	text := `
+name: retries
+hosts: webservers
+tasks:
+- name: download
+  action: fetch
+  retries: 3
+  delay: 5
+- name: notify
+  kind: service
+  action: notify
+  retries: 1`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) || !assert.Len(t, pr.Tasks, 2) {
+		return
+	}
+
+	task := pr.Tasks[0].(*Task)
+	assert.Equal(t, 3, task.Retries)
+	assert.Equal(t, 5, task.Delay)
+
+	sv := pr.Tasks[1].(*Service)
+	assert.Equal(t, 1, sv.Retries)
+	assert.Equal(t, 0, sv.Delay)
+}
+
+func TestProcess_UnmarshalNoLog(t *testing.T) {
+	text := `
+name: no_log
+hosts: webservers
+tasks:
+- name: login
+  action: login
+  no_log: true
+- name: notify
+  kind: service
+  action: notify
+  no_log: true
+- name: ping
+  action: ping`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) || !assert.Len(t, pr.Tasks, 3) {
+		return
+	}
+
+	assert.True(t, pr.Tasks[0].(*Task).NoLog)
+	assert.True(t, pr.Tasks[1].(*Service).NoLog)
+	assert.False(t, pr.Tasks[2].(*Task).NoLog)
+}
+
+func TestProcess_AssignIds(t *testing.T) {
+	text := `
+name: ids
+hosts: webservers
+tasks:
+- name: ping
+  id: ping
+  action: ping
+- name: child
+  kind: process
+  tasks:
+  - name: notify
+    kind: service
+    action: notify
+handlers:
+- name: restart
+  action: restart`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pr.AssignIds()
+	child := pr.Tasks[1].(*ChildProcess)
+	assert.NotEmpty(t, pr.Id)
+	assert.Equal(t, "ping", pr.Tasks[0].(*Task).Id)
+	assert.NotEmpty(t, child.Id)
+	assert.NotEmpty(t, child.Tasks[0].(*Service).Id)
+	assert.NotEmpty(t, pr.Handlers[0].Id)
+
+	// the ids are kept by the yaml of process
+	data, err := yaml.Marshal(pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	out := &Process{}
+	if !assert.NoError(t, yaml.Unmarshal(data, out)) {
+		return
+	}
+	assert.Equal(t, pr.Id, out.Id)
+	assert.Equal(t, child.Tasks[0].(*Service).Id, out.Tasks[1].(*ChildProcess).Tasks[0].(*Service).Id)
+	assert.Equal(t, pr.Handlers[0].Id, out.Handlers[0].Id)
+}
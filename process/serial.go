@@ -0,0 +1,210 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SplitBatches splits hosts into batches by serial. Each item of serial is a
+// count ("5") or a percentage of all hosts ("25%"), the items are applied to
+// the batches in turn and the last one repeats until all hosts are done.
+// All hosts are in a single batch when serial is empty.
+func SplitBatches(hosts []string, serial []string) ([][]string, error) {
+	if len(hosts) == 0 {
+		return [][]string{}, nil
+	}
+	if len(serial) == 0 {
+		return [][]string{hosts}, nil
+	}
+
+	sizes := make([]int, 0, len(serial))
+	for _, item := range serial {
+		size, err := parseBatchSize(item, len(hosts))
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+
+	batches := make([][]string, 0)
+	for i, offset := 0, 0; offset < len(hosts); i++ {
+		size := sizes[len(sizes)-1]
+		if i < len(sizes) {
+			size = sizes[i]
+		}
+
+		end := offset + size
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		batches = append(batches, hosts[offset:end])
+		offset = end
+	}
+
+	return batches, nil
+}
+
+// BatchFailed returns true if the failed hosts of a batch abort the rollout. The rollout is
+// aborted when the percentage of failed hosts exceeds MaxFailPercentage, or all hosts of
+// the batch fail if MaxFailPercentage isn't set, e.g. max_fail_percentage: 0 aborts it by
+// any failed host.
+func (p *Process) BatchFailed(failed, total int) bool {
+	if failed == 0 {
+		return false
+	}
+	if p.MaxFailPercentage == nil {
+		return failed >= total
+	}
+	return failed*100 > *p.MaxFailPercentage*total
+}
+
+// parseBatchSize parses the size of batch from count or percentage, the size is at least 1
+func parseBatchSize(item string, total int) (int, error) {
+	text := strings.TrimSpace(item)
+	percent := strings.HasSuffix(text, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid serial '%s'", item)
+	}
+
+	size := int(value)
+	if percent {
+		size = int(float64(total) * value / 100)
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size, nil
+}
+
+// parseSerial normalizes serial from yaml, it could be a count, a percentage or a list of them
+func parseSerial(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+
+	serial := make([]string, 0, len(items))
+	for _, item := range items {
+		switch tt := item.(type) {
+		case int, int64, uint64, float64:
+			serial = append(serial, fmt.Sprintf("%v", tt))
+		case string:
+			serial = append(serial, strings.TrimSpace(tt))
+		default:
+			return nil, fmt.Errorf("invalid serial '%v'", item)
+		}
+	}
+
+	for _, item := range serial {
+		if _, err := parseBatchSize(item, 1); err != nil {
+			return nil, err
+		}
+	}
+	return serial, nil
+}
+
+// Segments splits the process at the child processes which have serial,
+// each of them runs as a standalone Process over its own batches of hosts,
+// and the other tasks between them keep running with the settings of the process.
+func (p *Process) Segments() []*Process {
+	segments := make([]*Process, 0)
+	var current *Process
+	for _, task := range p.Tasks {
+		cp, ok := task.(*ChildProcess)
+		if !ok || len(cp.Serial) == 0 {
+			if current == nil {
+				current = p.segment()
+			}
+			current.Tasks = append(current.Tasks, task)
+			continue
+		}
+
+		if current != nil {
+			segments = append(segments, current)
+			current = nil
+		}
+		segments = append(segments, p.childSegment(cp))
+	}
+
+	if len(segments) == 0 {
+		return []*Process{p}
+	}
+	if current != nil {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+func (p *Process) segment() *Process {
+	return &Process{
+		Name:              p.Name,
+		Id:                p.Id,
+		Desc:              p.Desc,
+		Hosts:             p.Hosts,
+		Vars:              p.Vars,
+		RemoteUser:        p.RemoteUser,
+		Sudo:              p.Sudo,
+		SudoUser:          p.SudoUser,
+		Serial:            p.Serial,
+		MaxFailPercentage: p.MaxFailPercentage,
+		Tasks:             []ITask{},
+		Handlers:          p.Handlers,
+		when:              p.when,
+	}
+}
+
+func (p *Process) childSegment(cp *ChildProcess) *Process {
+	segment := &Process{
+		Name:              cp.Name,
+		Id:                cp.Id,
+		Desc:              cp.Desc,
+		Hosts:             cp.Hosts,
+		Vars:              map[string]any{},
+		RemoteUser:        cp.RemoteUser,
+		Sudo:              p.Sudo || cp.Sudo,
+		SudoUser:          cp.SudoUser,
+		Serial:            cp.Serial,
+		MaxFailPercentage: cp.MaxFailPercentage,
+		Tasks:             cp.Tasks,
+		Handlers:          append(append([]*Handler{}, p.Handlers...), cp.Handlers...),
+		when:              p.when,
+	}
+	if cp.When != "" {
+		segment.when = append(p.when[:len(p.when):len(p.when)], cp.When)
+	}
+	if len(segment.Hosts) == 0 {
+		segment.Hosts = p.Hosts
+	}
+	for key, value := range p.Vars {
+		segment.Vars[key] = value
+	}
+	for key, value := range cp.Vars {
+		segment.Vars[key] = value
+	}
+	if segment.RemoteUser == "" {
+		segment.RemoteUser = p.RemoteUser
+	}
+	if segment.SudoUser == "" {
+		segment.SudoUser = p.SudoUser
+	}
+	return segment
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSplitBatches(t *testing.T) {
+	hosts := []string{"h1", "h2", "h3", "h4", "h5", "h6", "h7", "h8", "h9", "h10"}
+
+	cases := []struct {
+		name    string
+		serial  []string
+		batches [][]string
+	}{
+		{"empty", nil, [][]string{hosts}},
+		{"count", []string{"4"}, [][]string{hosts[:4], hosts[4:8], hosts[8:]}},
+		{"percentage", []string{"30%"}, [][]string{hosts[:3], hosts[3:6], hosts[6:9], hosts[9:]}},
+		{"small percentage", []string{"1%"}, [][]string{
+			hosts[:1], hosts[1:2], hosts[2:3], hosts[3:4], hosts[4:5],
+			hosts[5:6], hosts[6:7], hosts[7:8], hosts[8:9], hosts[9:],
+		}},
+		{"ramp", []string{"1", "3", "50%"}, [][]string{hosts[:1], hosts[1:4], hosts[4:9], hosts[9:]}},
+		{"oversize", []string{"20"}, [][]string{hosts}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			batches, err := SplitBatches(hosts, c.serial)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, c.batches, batches)
+		})
+	}
+
+	_, err := SplitBatches(hosts, []string{"ten"})
+	assert.Error(t, err)
+}
+
+func TestProcess_UnmarshalSerial(t *testing.T) {
+	text := `
+name: rolling upgrade
+hosts: webservers
+serial: [1, 5, "25%"]
+max_fail_percentage: 30
+tasks:
+- name: upgrade
+  action: ping
+- name: restart
+  kind: process
+  serial: 2
+  tasks:
+  - name: restart service
+    action: ping`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"1", "5", "25%"}, pr.Serial)
+	if assert.NotNil(t, pr.MaxFailPercentage) {
+		assert.Equal(t, 30, *pr.MaxFailPercentage)
+	}
+
+	if assert.Len(t, pr.Tasks, 2) {
+		cp, ok := pr.Tasks[1].(*ChildProcess)
+		if assert.True(t, ok) {
+			assert.Equal(t, []string{"2"}, cp.Serial)
+		}
+	}
+
+	err = yaml.Unmarshal([]byte("serial: [1, true]"), &Process{})
+	assert.Error(t, err)
+}
+
+func TestProcess_Segments(t *testing.T) {
+	child := NewChildProcessBuilder().
+		Named("c1", "rolling child", "").
+		SetSerial("1").
+		SetMaxFailPercentage(50).
+		SetTasks(NewTaskBuilder().Named("t2", "", "").Build()).
+		Build()
+	pr := NewProcessBuilder().
+		Named("p1", "process", "").
+		SetHosts("web").
+		SetVar("a", "b").
+		SetTasks(
+			NewTaskBuilder().Named("t1", "", "").Build(),
+			child,
+			NewTaskBuilder().Named("t3", "", "").Build(),
+		).
+		Build()
+
+	segments := pr.Segments()
+	if !assert.Len(t, segments, 3) {
+		return
+	}
+	assert.Equal(t, "p1", segments[0].Id)
+	assert.Len(t, segments[0].Tasks, 1)
+
+	assert.Equal(t, "c1", segments[1].Id)
+	assert.Equal(t, []string{"web"}, segments[1].Hosts)
+	assert.Equal(t, []string{"1"}, segments[1].Serial)
+	if assert.NotNil(t, segments[1].MaxFailPercentage) {
+		assert.Equal(t, 50, *segments[1].MaxFailPercentage)
+	}
+	assert.Equal(t, "b", segments[1].Vars["a"])
+
+	assert.Equal(t, "p1", segments[2].Id)
+	assert.Len(t, segments[2].Tasks, 1)
+
+	single := NewProcessBuilder().SetTasks(NewTaskBuilder().Build()).Build()
+	assert.Equal(t, []*Process{single}, single.Segments())
+}
+
+func TestProcess_BatchFailed(t *testing.T) {
+	pr := &Process{}
+	// all hosts of batch must fail by default
+	assert.False(t, pr.BatchFailed(0, 2))
+	assert.False(t, pr.BatchFailed(1, 2))
+	assert.True(t, pr.BatchFailed(2, 2))
+
+	pr = NewProcessBuilder().SetMaxFailPercentage(0).Build()
+	assert.False(t, pr.BatchFailed(0, 4))
+	assert.True(t, pr.BatchFailed(1, 4))
+
+	pr = NewProcessBuilder().SetMaxFailPercentage(50).Build()
+	assert.False(t, pr.BatchFailed(2, 4))
+	assert.True(t, pr.BatchFailed(3, 4))
+}
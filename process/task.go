@@ -54,6 +54,15 @@ type ChildProcess struct {
 	Sudo     bool   `json:"sudo,omitempty" yaml:"sudo,omitempty"`
 	SudoUser string `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
 
+	// Serial runs the child process over batches of hosts, see SplitBatches
+	Serial []string `json:"serial,omitempty" yaml:"serial,omitempty"`
+	// MaxFailPercentage aborts the rollout when the percentage of failed hosts in a batch exceeds it,
+	// the rollout is aborted only when all hosts of a batch fail if it isn't set. See BatchFailed
+	MaxFailPercentage *int `json:"max_fail_percentage,omitempty" yaml:"max_fail_percentage,omitempty"`
+
+	// When is the tengo expression, the tasks of child process are skipped on the hosts which it is false
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+
 	Tasks []ITask `json:"tasks,omitempty" yaml:"tasks,omitempty"`
 
 	Handlers []*Handler `json:"handlers,omitempty" yaml:"handlers,omitempty"`
@@ -121,6 +130,29 @@ func (p *ChildProcess) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "serial" {
+			p.Serial, err = parseSerial(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "max_fail_percentage" {
+			var percentage int
+			_, err = kv.Apply("max_fail_percentage", &percentage)
+			if err != nil {
+				return
+			}
+			p.MaxFailPercentage = &percentage
+			continue
+		}
+		if key == "when" {
+			p.When, err = parseWhen(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
 
 		if key == "tasks" {
 			vv, ok := value.([]any)
@@ -192,11 +224,32 @@ type Task struct {
 	SudoUser string `json:"sudo_user,omitempty" yaml:"sudo_user,omitempty"`
 
 	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Forks limits the number of hosts running the task at the same time
+	Forks int `json:"forks,omitempty" yaml:"forks,omitempty"`
+
+	// When is the tengo expression, the task is skipped on the hosts which it is false
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// Loop runs the action once per item, it is a list or a tengo expression resolving to a list
+	Loop any `json:"loop,omitempty" yaml:"loop,omitempty"`
+	// LoopVar is the name of variable which holds the item in Args, defaults to DefaultLoopVar
+	LoopVar string `json:"loop_var,omitempty" yaml:"loop_var,omitempty"`
 
 	Catch  *Handler `json:"catch,omitempty" yaml:"catch,omitempty"`
 	Finish *Handler `json:"finish,omitempty" yaml:"finish,omitempty"`
 
 	Notify []string `json:"notify,omitempty" yaml:"notify,omitempty"`
+
+	// Register stores the result of each host by the name, the later tasks refer to it as "register.<name>"
+	Register string `json:"register,omitempty" yaml:"register,omitempty"`
+
+	// Retries is the max number of reruns on the host which the action fails
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Delay is the seconds to wait before each rerun
+	Delay int `json:"delay,omitempty" yaml:"delay,omitempty"`
+
+	// NoLog hides the output of action from the logs and callbacks
+	NoLog bool `json:"no_log,omitempty" yaml:"no_log,omitempty"`
 }
 
 func (t *Task) fromKV(kv YamlKV) (err error) {
@@ -229,6 +282,13 @@ func (t *Task) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "forks" {
+			_, err = kv.Apply("forks", &t.Forks)
+			if err != nil {
+				return
+			}
+			continue
+		}
 		if key == "vars" {
 			_, err = kv.ApplyMap("vars", &t.Vars)
 			if err != nil {
@@ -267,6 +327,55 @@ func (t *Task) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "register" {
+			_, err = kv.Apply("register", &t.Register)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "retries" {
+			_, err = kv.Apply("retries", &t.Retries)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "delay" {
+			_, err = kv.Apply("delay", &t.Delay)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "no_log" {
+			_, err = kv.Apply("no_log", &t.NoLog)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "when" {
+			t.When, err = parseWhen(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "loop" {
+			t.Loop, err = parseLoop(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "loop_var" {
+			_, err = kv.Apply("loop_var", &t.LoopVar)
+			if err != nil {
+				return
+			}
+			continue
+		}
 
 		if key == "catch" {
 			if vv, ok := value.(YamlKV); ok {
@@ -295,6 +404,12 @@ func (t *Task) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "args" {
+			if ykv, ok := value.(YamlKV); ok {
+				t.Args = ykv
+			}
+			continue
+		}
 
 		if t.Action == "" {
 			t.Action = key
@@ -339,6 +454,16 @@ type Service struct {
 	Vars map[string]any `json:"vars,omitempty" yaml:"vars,omitempty"`
 
 	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Forks limits the number of hosts running the service at the same time
+	Forks int `json:"forks,omitempty" yaml:"forks,omitempty"`
+
+	// When is the tengo expression, the service is skipped on the hosts which it is false
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// Loop calls the service once per item, it is a list or a tengo expression resolving to a list
+	Loop any `json:"loop,omitempty" yaml:"loop,omitempty"`
+	// LoopVar is the name of variable which holds the item in Args, defaults to DefaultLoopVar
+	LoopVar string `json:"loop_var,omitempty" yaml:"loop_var,omitempty"`
 
 	Action string         `json:"action,omitempty" yaml:"action,omitempty"`
 	Args   map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
@@ -347,6 +472,17 @@ type Service struct {
 	Finish *Handler `json:"finish,omitempty" yaml:"finish,omitempty"`
 
 	Notify []string `json:"notify,omitempty" yaml:"notify,omitempty"`
+
+	// Register stores the result of each host by the name, the later tasks refer to it as "register.<name>"
+	Register string `json:"register,omitempty" yaml:"register,omitempty"`
+
+	// Retries is the max number of reruns on the host which the action fails
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Delay is the seconds to wait before each rerun
+	Delay int `json:"delay,omitempty" yaml:"delay,omitempty"`
+
+	// NoLog hides the output of action from the logs and callbacks
+	NoLog bool `json:"no_log,omitempty" yaml:"no_log,omitempty"`
 }
 
 func (s *Service) fromKV(kv YamlKV) (err error) {
@@ -360,7 +496,7 @@ func (s *Service) fromKV(kv YamlKV) (err error) {
 			continue
 		}
 		if key == "id" {
-			_, err = kv.Apply("id", &s.Name)
+			_, err = kv.Apply("id", &s.Id)
 			if err != nil {
 				return err
 			}
@@ -380,6 +516,13 @@ func (s *Service) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "forks" {
+			_, err = kv.Apply("forks", &s.Forks)
+			if err != nil {
+				return
+			}
+			continue
+		}
 		if key == "vars" {
 			_, err = kv.ApplyMap("vars", &s.Vars)
 			if err != nil {
@@ -394,6 +537,55 @@ func (s *Service) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "register" {
+			_, err = kv.Apply("register", &s.Register)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "retries" {
+			_, err = kv.Apply("retries", &s.Retries)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "delay" {
+			_, err = kv.Apply("delay", &s.Delay)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "no_log" {
+			_, err = kv.Apply("no_log", &s.NoLog)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "when" {
+			s.When, err = parseWhen(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "loop" {
+			s.Loop, err = parseLoop(value)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if key == "loop_var" {
+			_, err = kv.Apply("loop_var", &s.LoopVar)
+			if err != nil {
+				return
+			}
+			continue
+		}
 		if key == "kind" {
 			continue
 		}
@@ -425,8 +617,14 @@ func (s *Service) fromKV(kv YamlKV) (err error) {
 			}
 			continue
 		}
+		if key == "args" {
+			if ykv, ok := value.(YamlKV); ok {
+				s.Args = ykv
+			}
+			continue
+		}
 
-		if key == "" {
+		if s.Action == "" {
 			s.Action = key
 		}
 		if vs, ok := value.(string); ok {
@@ -253,6 +253,42 @@ func DecodeServiceTask(props, headers map[string]any) *Service {
 	return s
 }
 
+// EncodeHandlers encodes the handlers into the property of process
+func EncodeHandlers(handlers []*Handler) string {
+	data, _ := json.Marshal(handlers)
+	return string(data)
+}
+
+// DecodeHandlers decodes the handlers from the property of process
+func DecodeHandlers(text string) ([]*Handler, error) {
+	handlers := make([]*Handler, 0)
+	if text == "" {
+		return handlers, nil
+	}
+	if err := json.Unmarshal([]byte(text), &handlers); err != nil {
+		return nil, fmt.Errorf("decode handlers: %v", err)
+	}
+	return handlers, nil
+}
+
+// EncodeVars encodes the variables into the property of process
+func EncodeVars(vars map[string]any) string {
+	data, _ := json.Marshal(vars)
+	return string(data)
+}
+
+// DecodeVars decodes the variables from the property of process
+func DecodeVars(text string) (map[string]any, error) {
+	vars := map[string]any{}
+	if text == "" {
+		return vars, nil
+	}
+	if err := json.Unmarshal([]byte(text), &vars); err != nil {
+		return nil, fmt.Errorf("decode vars: %v", err)
+	}
+	return vars, nil
+}
+
 func newSnoId() string {
-	return string(sno.New(0).Bytes())
+	return sno.New(0).String()
 }
@@ -0,0 +1,65 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseWhen normalizes the condition from yaml, it could be an expression, a boolean
+// or a list of expressions which must be all true.
+func parseWhen(value any) (string, error) {
+	switch tt := value.(type) {
+	case nil:
+		return "", nil
+	case bool:
+		return fmt.Sprintf("%v", tt), nil
+	case string:
+		return strings.TrimSpace(tt), nil
+	case []any:
+		items := make([]string, 0, len(tt))
+		for _, item := range tt {
+			text, err := parseWhen(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return joinWhen(items...), nil
+	default:
+		return "", fmt.Errorf("invalid when '%v'", value)
+	}
+}
+
+// joinWhen joins the conditions by "&&", the empty ones are ignored
+func joinWhen(conditions ...string) string {
+	items := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		if condition = strings.TrimSpace(condition); condition != "" {
+			items = append(items, condition)
+		}
+	}
+	if len(items) == 1 {
+		return items[0]
+	}
+	for i, item := range items {
+		items[i] = "(" + item + ")"
+	}
+	return strings.Join(items, " && ")
+}
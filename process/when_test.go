@@ -0,0 +1,76 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProcess_UnmarshalWhen(t *testing.T) {
+	text := `
+name: conditional
+hosts: webservers
+tasks:
+- name: install
+  action: ping
+  when: os == "linux"
+- name: upgrade
+  action: ping
+  when: [os == "linux", version != "2.0"]
+- name: never
+  kind: service
+  action: ping
+  when: false`
+
+	pr := &Process{}
+	err := yaml.Unmarshal([]byte(text), pr)
+	if !assert.NoError(t, err) || !assert.Len(t, pr.Tasks, 3) {
+		return
+	}
+	assert.Equal(t, `os == "linux"`, pr.Tasks[0].(*Task).When)
+	assert.Equal(t, `(os == "linux") && (version != "2.0")`, pr.Tasks[1].(*Task).When)
+	assert.Equal(t, `false`, pr.Tasks[2].(*Service).When)
+
+	err = yaml.Unmarshal([]byte("tasks: [{action: ping, when: {a: b}}]"), &Process{})
+	assert.Error(t, err)
+}
+
+func TestProcess_BuildWhen(t *testing.T) {
+	task := NewTaskBuilder().Named("t1", "", "").SetWhen("b").SetVar("x", 2).Build()
+	child := NewChildProcessBuilder().
+		Named("c1", "", "").
+		SetWhen("a").
+		SetVar("x", 1).
+		SetVar("y", 1).
+		SetTasks(task).
+		Build()
+
+	sc := scope{}.child(child)
+	out := sc.applyTask(task)
+	assert.Equal(t, "(a) && (b)", out.When)
+	assert.Equal(t, map[string]any{"x": 2, "y": 1}, out.Vars)
+	// the task itself isn't changed
+	assert.Equal(t, "b", task.When)
+
+	sv := NewServiceBuilder().Named("s1", "", "").Build()
+	assert.Equal(t, "a", sc.applyService(sv).When)
+	assert.Equal(t, "", scope{}.applyService(sv).When)
+}
@@ -19,12 +19,33 @@ package bee_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/olive-io/bpmn/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 
 	"github.com/olive-io/bee"
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/history"
+	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
 	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+	"github.com/olive-io/bee/vars"
 )
 
 func TestRuntime_Play(t *testing.T) {
@@ -49,7 +70,7 @@ func TestRuntime_Play(t *testing.T) {
 			},
 		},
 	}
-	err := rt.Play(ctx, pr, options...)
+	_, err := rt.Play(ctx, pr, options...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -62,7 +83,6 @@ func TestRuntime_PlayWithService(t *testing.T) {
 
 	ctx := context.TODO()
 	options := make([]bee.RunOption, 0)
-	options = append(options)
 	inventory.AddSources(sources...)
 
 	pr := &process.Process{
@@ -127,7 +147,7 @@ func TestRuntime_PlayWithService(t *testing.T) {
 			},
 		},
 	}
-	err := rt.Play(ctx, pr, options...)
+	_, err := rt.Play(ctx, pr, options...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -164,8 +184,1341 @@ func TestRuntime_PlayWithTracer(t *testing.T) {
 			t.Logf("%#v", tt)
 		}
 	}()
-	err := rt.Play(ctx, pr, options...)
+	_, err := rt.Play(ctx, pr, options...)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+// newServiceRuntime creates bee.Runtime which calls the services by caller, it needs no remote hosts
+func newServiceRuntime(t *testing.T, hostText string, caller bee.Callable, opts ...bee.Option) *bee.Runtime {
+	dir := t.TempDir()
+	for _, name := range []string{"repl", "modules"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataloader := parser.NewDataLoader()
+	if err := dataloader.ParseString(hostText); err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := inv.NewInventoryManager(dataloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := vars.NewVariablesManager(dataloader, inventory)
+
+	options := []bee.Option{
+		bee.SetDir(dir),
+		bee.SetLogger(zap.NewNop()),
+		bee.SetCaller(caller),
+	}
+	options = append(options, opts...)
+	rt, err := bee.NewRuntime(inventory, variables, dataloader, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rt.Stop() })
+	return rt
+}
+
+type resultRecorder struct {
+	callback.BaseCallBack
+
+	mu          sync.Mutex
+	results     []*stats.TaskResult
+	skipped     []*stats.TaskResult
+	unreachable []*stats.TaskResult
+}
+
+func (r *resultRecorder) RunnerOnOk(result *stats.TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *resultRecorder) RunnerOkFailed(result *stats.TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *resultRecorder) RunnerOnUnreachable(result *stats.TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unreachable = append(r.unreachable, result)
+}
+
+func (r *resultRecorder) RunnerOnSkipped(result *stats.TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped = append(r.skipped, result)
+}
+
+func TestRuntime_PlayConcurrent(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+h4
+h5
+h6
+
+[web]
+h1
+h2
+h3
+h4
+h5
+h6
+`
+	var running, peak int32
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			last := atomic.LoadInt32(&peak)
+			if current <= last || atomic.CompareAndSwapInt32(&peak, last, current) {
+				break
+			}
+		}
+
+		// the first host is the slowest one
+		if host == "h1" {
+			time.Sleep(time.Millisecond * 200)
+		} else {
+			time.Sleep(time.Millisecond * 50)
+		}
+		if host == "h3" {
+			return nil, errors.New("h3 failed")
+		}
+		return []byte(`{"host": "` + host + `"}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(4))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "concurrent process", "").
+		SetHosts("web").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "first service", "").
+			SetForks(2).
+			SetAction("echo", map[string]any{}).
+			Build()).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "h3 failed")
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+	hosts := make([]string, 0)
+	for _, result := range recorder.results {
+		hosts = append(hosts, result.Host)
+	}
+	assert.Equal(t, []string{"h1", "h2", "h3", "h4", "h5", "h6"}, hosts)
+	assert.NotEmpty(t, recorder.results[2].ErrMsg)
+}
+
+func TestRuntime_PlaySerial(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+h4
+h5
+h6
+h7
+h8
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, host)
+		mu.Unlock()
+
+		switch host {
+		case "h3", "h5", "h6":
+			return nil, errors.New(host + " failed")
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "rolling process", "").
+		SetHosts("h*").
+		SetSerial("2").
+		SetMaxFailPercentage(50).
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "upgrade", "").
+			SetAction("upgrade", map[string]any{}).
+			Build()).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr)
+	var be *bee.BatchError
+	if !assert.ErrorAs(t, err, &be) {
+		return
+	}
+	assert.Equal(t, 2, be.Index)
+	assert.Equal(t, []string{"h5", "h6"}, be.Hosts)
+	assert.Equal(t, []string{"h5", "h6"}, be.Failed)
+
+	// h3 failed but it doesn't exceed max fail percentage, h7 and h8 never run
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"h1", "h2", "h3", "h4", "h5", "h6"}, called)
+}
+
+func TestRuntime_PlaySerialAllFailed(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+h4
+h5
+h6
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, host)
+		mu.Unlock()
+
+		switch host {
+		case "h1", "h3", "h4":
+			return nil, errors.New(host + " failed")
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "rolling process", "").
+		SetHosts("h*").
+		SetSerial("2").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "upgrade", "").
+			SetAction("upgrade", map[string]any{}).
+			Build()).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr)
+	var be *bee.BatchError
+	if !assert.ErrorAs(t, err, &be) {
+		return
+	}
+	// the rollout is aborted only when all hosts of batch fail without max_fail_percentage
+	assert.Equal(t, 1, be.Index)
+	assert.Equal(t, []string{"h3", "h4"}, be.Failed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"h1", "h2", "h3", "h4"}, called)
+}
+
+func TestRuntime_PlaySerialTasks(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+h4
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host)
+		mu.Unlock()
+
+		if action == "a1" && host == "h1" {
+			return nil, errors.New("a1 failed")
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "rolling process", "").
+		SetHosts("h*").
+		SetSerial("2").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "a1", "").
+				SetAction("a1", map[string]any{}).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "a2", "").
+				SetAction("a2", map[string]any{}).
+				Build(),
+		).
+		Build()
+
+	report, err := rt.Play(context.TODO(), pr)
+	// the batch is tolerated, h2 keeps running the later tasks
+	assert.Error(t, err)
+	var be *bee.BatchError
+	assert.False(t, errors.As(err, &be))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"a1@h1", "a1@h2", "a2@h2", "a1@h3", "a1@h4", "a2@h3", "a2@h4"}, called)
+	assert.Equal(t, int64(1), report.Stats.Get(bexecutor.Failures, "h1"))
+	assert.Equal(t, int64(0), report.Stats.Get(bexecutor.Ok, "h1"))
+	assert.Equal(t, int64(2), report.Stats.Get(bexecutor.Ok, "h2"))
+	assert.Equal(t, int64(0), report.Stats.Get(bexecutor.Failures, "h2"))
+	assert.Equal(t, int64(2), report.Stats.Get(bexecutor.Ok, "h4"))
+}
+
+func TestRuntime_PlayNotify(t *testing.T) {
+	hostText := `
+h1
+h2
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host)
+		mu.Unlock()
+
+		switch action {
+		case "install":
+			// only h1 is changed
+			return []byte(`{"changed": ` + strconv.FormatBool(host == "h1") + `}`), nil
+		case "configure":
+			return []byte(`{"changed": "true"}`), nil
+		}
+		return []byte(`{}`), nil
+	}
+	// runs hosts one by one to keep the order of calls
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	restart := process.NewHandlerBuilder().
+		Named("", "restart", "").
+		SetKind(process.ServiceKey).
+		SetAction("restart", map[string]any{}).
+		Build()
+	reload := process.NewHandlerBuilder().
+		Named("", "reload", "").
+		SetKind(process.ServiceKey).
+		SetAction("reload", map[string]any{}).
+		Build()
+	pr := process.NewProcessBuilder().
+		Named("p1", "notify process", "").
+		SetHosts("h*").
+		SetHandlers(restart, reload).
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetAction("install", map[string]any{}).
+				SetNotify("restart").
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "install again", "").
+				SetAction("install", map[string]any{}).
+				SetNotify("restart").
+				Build(),
+			process.NewTaskBuilder().
+				Named("t1", "flush", "").
+				SetAction(process.FlushHandlersAction, map[string]any{}).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s3", "configure", "").
+				SetAction("configure", map[string]any{}).
+				SetNotify("reload", "unknown").
+				Build(),
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"install@h1", "install@h2",
+		"install@h1", "install@h2",
+		"restart@h1",
+		"configure@h1", "configure@h2",
+		"reload@h1", "reload@h2",
+	}, called)
+
+	tasks := make([]string, 0)
+	for _, result := range recorder.results {
+		tasks = append(tasks, result.Task+"@"+result.Host)
+	}
+	assert.Contains(t, tasks, "restart@h1")
+	assert.Contains(t, tasks, "reload@h2")
+	assert.NotContains(t, tasks, "restart@h2")
+}
+
+func TestRuntime_PlayWhen(t *testing.T) {
+	hostText := `
+h1 os=linux
+h2 os=windows
+h3 os=linux
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host)
+		mu.Unlock()
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	child := process.NewChildProcessBuilder().
+		Named("c1", "child", "").
+		SetWhen(`port > 8000`).
+		SetTasks(process.NewServiceBuilder().
+			Named("s3", "listen", "").
+			SetAction("listen", map[string]any{}).
+			Build()).
+		Build()
+	pr := process.NewProcessBuilder().
+		Named("p1", "when process", "").
+		SetHosts("h*").
+		SetVar("port", 8080).
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetWhen(`os == "linux" && vars.port == 8080`).
+				SetAction("install", map[string]any{}).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "upgrade", "").
+				SetVar("version", "2.0").
+				SetWhen(`import("text").has_prefix(version, "1.")`).
+				SetAction("upgrade", map[string]any{}).
+				Build(),
+			child,
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"install@h1", "install@h3", "listen@h1", "listen@h2", "listen@h3"}, called)
+
+	skipped := make([]string, 0)
+	for _, result := range recorder.skipped {
+		skipped = append(skipped, result.Task+"@"+result.Host)
+	}
+	assert.Equal(t, []string{"install@h2", "upgrade@h1", "upgrade@h2", "upgrade@h3"}, skipped)
+
+	pr = process.NewProcessBuilder().
+		Named("p2", "invalid when process", "").
+		SetHosts("h1").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "install", "").
+			SetWhen(`missing == 1`).
+			SetAction("install", map[string]any{}).
+			Build()).
+		Build()
+	_, err = rt.Play(context.TODO(), pr)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "task 'install' on host 'h1'")
+	}
+}
+
+func TestRuntime_PlayLoop(t *testing.T) {
+	hostText := `
+h1
+h2
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		args := map[string]any{}
+		_ = json.Unmarshal(in, &args)
+		name, _ := args["name"].(string)
+
+		mu.Lock()
+		called = append(called, name+"@"+host)
+		mu.Unlock()
+
+		if name == "bob" && host == "h2" {
+			return nil, errors.New("bob exists")
+		}
+		return []byte(`{"changed": ` + strconv.FormatBool(name == "alice") + `}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "loop process", "").
+		SetHosts("h*").
+		SetVar("users", []any{"alice", "bob"}).
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "create users", "").
+				SetLoop("users", "user").
+				SetAction("useradd", map[string]any{"name": "{{ user }}"}).
+				Build(),
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "bob exists")
+	}
+
+	assert.Equal(t, []string{"alice@h1", "bob@h1", "alice@h2", "bob@h2"}, called)
+
+	if !assert.Len(t, recorder.results, 2) {
+		return
+	}
+	result := recorder.results[0]
+	assert.Equal(t, "h1", result.Host)
+	assert.True(t, result.Changed)
+	assert.Equal(t, []any{
+		map[string]any{"changed": true, "user": "alice"},
+		map[string]any{"changed": false, "user": "bob"},
+	}, result.Stdout["results"])
+
+	result = recorder.results[1]
+	assert.Equal(t, "h2", result.Host)
+	assert.NotEmpty(t, result.ErrMsg)
+	if results, ok := result.Stdout["results"].([]any); assert.True(t, ok) && assert.Len(t, results, 2) {
+		assert.Equal(t, true, results[1].(map[string]any)["failed"])
+	}
+
+	pr = process.NewProcessBuilder().
+		Named("p2", "literal loop process", "").
+		SetHosts("h1").
+		SetVar("users", []any{"carol"}).
+		SetTasks(process.NewServiceBuilder().
+			Named("s2", "create groups", "").
+			SetLoop([]any{"{{ users[0] }}", "staff"}, "").
+			SetAction("groupadd", map[string]any{"name": "{{ item }}"}).
+			Build()).
+		Build()
+	called = called[:0]
+	_, err = rt.Play(context.TODO(), pr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol@h1", "staff@h1"}, called)
+}
+
+func TestRuntime_PlayRegister(t *testing.T) {
+	hostText := `
+h1
+h2
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host+":"+string(in))
+		mu.Unlock()
+
+		if action == "read" {
+			return []byte(`{"data": "from ` + host + `"}`), nil
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "register process", "").
+		SetHosts("h*").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "read", "").
+				SetAction("read", map[string]any{}).
+				SetRegister("previous").
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "skip", "").
+				SetWhen(`false`).
+				SetAction("skip", map[string]any{}).
+				SetRegister("skipped").
+				Build(),
+			process.NewServiceBuilder().
+				Named("s3", "write", "").
+				SetWhen(`register.skipped.skipped`).
+				SetAction("write", map[string]any{"text": "got {{ register.previous.data }}"}).
+				Build(),
+		).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{
+		"read@h1:{}", "read@h2:{}",
+		`write@h1:{"text":"got from h1"}`, `write@h2:{"text":"got from h2"}`,
+	}, called)
+}
+
+func TestRuntime_PlayRegisterSerial(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host+":"+string(in))
+		mu.Unlock()
+
+		switch action {
+		case "read":
+			return []byte(`{"data": "from ` + host + `"}`), nil
+		case "write":
+			return []byte(`{"size": 3}`), nil
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	rolling := process.NewChildProcessBuilder().
+		Named("c1", "rolling write", "").
+		SetSerial("2").
+		SetTasks(process.NewServiceBuilder().
+			Named("s2", "write", "").
+			SetAction("write", map[string]any{"text": "{{ register.previous.data }}"}).
+			SetRegister("written").
+			Build()).
+		Build()
+	pr := process.NewProcessBuilder().
+		Named("p1", "register process", "").
+		SetHosts("h*").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "read", "").
+				SetAction("read", map[string]any{}).
+				SetRegister("previous").
+				Build(),
+			rolling,
+			process.NewServiceBuilder().
+				Named("s3", "verify", "").
+				SetAction("verify", map[string]any{"size": "{{ register.written.size }}"}).
+				Build(),
+		).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the batches of serial read the outputs registered before them, and the later tasks read theirs
+	assert.Equal(t, []string{
+		"read@h1:{}", "read@h2:{}", "read@h3:{}",
+		`write@h1:{"text":"from h1"}`, `write@h2:{"text":"from h2"}`,
+		`write@h3:{"text":"from h3"}`,
+		`verify@h1:{"size":3}`, `verify@h2:{"size":3}`, `verify@h3:{"size":3}`,
+	}, called)
+}
+
+func TestRuntime_PlayTemplate(t *testing.T) {
+	hostText := `
+h1 version=1.0 port=80
+h2 version=1.0
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+"@"+host+":"+string(in))
+		mu.Unlock()
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	restart := process.NewHandlerBuilder().
+		Named("", "restart", "").
+		SetKind(process.ServiceKey).
+		SetAction("restart", map[string]any{"version": "{{ version }}"}).
+		Build()
+	pr := process.NewProcessBuilder().
+		Named("p1", "template process", "").
+		SetHosts("h*").
+		SetVar("version", "2.0").
+		SetHandlers(restart).
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "install", "").
+			SetVar("name", "nginx").
+			SetAction("install", map[string]any{"pkg": "{{ name }}-{{ version }}"}).
+			SetNotify("restart").
+			Build()).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunExtraVars(map[string]any{"version": "3.0"}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		`install@h1:{"pkg":"nginx-3.0"}`, `install@h2:{"pkg":"nginx-3.0"}`,
+		`restart@h1:{"version":"3.0"}`, `restart@h2:{"version":"3.0"}`,
+	}, called)
+
+	pr = process.NewProcessBuilder().
+		Named("p2", "undefined process", "").
+		SetHosts("h*").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "listen", "").
+			SetAction("listen", map[string]any{"port": "{{ port }}"}).
+			Build()).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err = rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "task 'listen' on host 'h2': undefined variable 'port'")
+	}
+	if assert.Len(t, recorder.results, 2) {
+		assert.Empty(t, recorder.results[0].ErrMsg)
+		assert.NotEmpty(t, recorder.results[1].ErrMsg)
+	}
+}
+
+func TestRuntime_PlayCheck(t *testing.T) {
+	hostText := `
+h1
+h2
+`
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		options := &bee.RunOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		if !options.Check {
+			return nil, fmt.Errorf("expect check mode")
+		}
+		if action == "restart" {
+			return json.Marshal(map[string]any{"skipped": true, "skip_reason": bee.NoCheckModeReason})
+		}
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1), bee.SetCheck(true))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "check process", "").
+		SetHosts("h*").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetAction("install", map[string]any{}).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "restart", "").
+				SetAction("restart", map[string]any{}).
+				Build(),
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, recorder.results, 2) {
+		assert.True(t, recorder.results[0].Changed)
+		assert.True(t, recorder.results[1].Changed)
+	}
+	if assert.Len(t, recorder.skipped, 2) {
+		assert.Equal(t, "restart", recorder.skipped[0].Task)
+		assert.Equal(t, bee.NoCheckModeReason, recorder.skipped[0].Stdout["skip_reason"])
+	}
+}
+
+func TestRuntime_PlayCheckCatch(t *testing.T) {
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		options := &bee.RunOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		mu.Lock()
+		called = append(called, action)
+		mu.Unlock()
+		if !options.Check || !options.Diff {
+			return nil, fmt.Errorf("expect check and diff mode of %s", action)
+		}
+		if action == "install" {
+			return nil, fmt.Errorf("install failed")
+		}
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, "h1\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "check process", "").
+		SetHosts("h1").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetAction("install", map[string]any{}).
+				SetCatch(process.NewHandlerBuilder().
+					Named("", "rollback", "").
+					SetKind(process.ServiceKey).
+					SetAction("rollback", map[string]any{}).
+					Build()).
+				SetFinish(process.NewHandlerBuilder().
+					Named("", "cleanup", "").
+					SetKind(process.ServiceKey).
+					SetAction("cleanup", map[string]any{}).
+					Build()).
+				Build(),
+		).
+		Build()
+
+	report, err := rt.Play(context.TODO(), pr, bee.WithRunCheck(true), bee.WithRunDiff(true))
+	assert.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"install", "rollback", "cleanup"}, called)
+	// the catch handler succeeded in check mode
+	assert.Equal(t, int64(1), report.Stats.Get(bexecutor.Rescued, "h1"))
+}
+
+func TestRuntime_PlayReport(t *testing.T) {
+	hostText := `
+h1
+h2
+h3
+`
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		switch host {
+		case "h2":
+			return nil, fmt.Errorf("dial tcp: %w", client.ErrConnect)
+		case "h3":
+			return nil, fmt.Errorf("exit status 1")
+		}
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "report process", "").
+		SetHosts("h*").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "skip", "").
+				SetWhen(`false`).
+				SetAction("skip", map[string]any{}).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "install", "").
+				SetAction("install", map[string]any{}).
+				Build(),
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	report, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	assert.Error(t, err)
+	if !assert.NotNil(t, report) {
+		return
+	}
+	assert.Equal(t, "report process", report.Process)
+	assert.True(t, report.Failed())
+
+	as := report.Stats
+	assert.Equal(t, []string{"h1", "h2", "h3"}, as.Hosts())
+	assert.Equal(t, map[string]int64{
+		"processed": 1, "ok": 1, "dark": 0, "changed": 1, "skipped": 1,
+		"rescued": 0, "ignored": 0, "failures": 0,
+	}, as.Summarize("h1"))
+	assert.Equal(t, int64(1), as.Get(bexecutor.Dark, "h2"))
+	assert.Equal(t, int64(0), as.Get(bexecutor.Failures, "h2"))
+	assert.Equal(t, int64(1), as.Get(bexecutor.Failures, "h3"))
+	assert.Len(t, recorder.unreachable, 1)
+}
+
+// eventRecorder records the events of callback in order
+type eventRecorder struct {
+	callback.BaseCallBack
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *eventRecorder) PlayOnStart(pr *process.Process) {
+	r.record("play start %s", pr.Name)
+}
+
+func (r *eventRecorder) PlayOnEnd(pr *process.Process, as *bexecutor.AggregateStats) {
+	r.record("play end %s ok=%d", pr.Name, as.Get(bexecutor.Ok, "h1"))
+}
+
+func (r *eventRecorder) TaskOnStart(task process.INamedTask, hosts []string) {
+	r.record("task start %s %v", task.GetName(), hosts)
+}
+
+func (r *eventRecorder) HandlerOnStart(handler *process.Handler, hosts []string) {
+	r.record("handler start %s %v", handler.Name, hosts)
+}
+
+func (r *eventRecorder) HandlerOnFinish(task process.INamedTask, handler *process.Handler, hosts []string) {
+	r.record("finish %s %s %v", task.GetName(), handler.Name, hosts)
+}
+
+func (r *eventRecorder) RunnerOnOk(result *stats.TaskResult) {
+	r.record("ok %s@%s", result.Task, result.Host)
+}
+
+func (r *eventRecorder) RunnerOnRetry(result *stats.TaskResult) {
+	r.record("retry %s@%s %d", result.Task, result.Host, result.Attempts)
+}
+
+func TestRuntime_PlayEvents(t *testing.T) {
+	hostText := `
+h1
+h2
+`
+	var attempts atomic.Int32
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		if action == "install" && host == "h1" && attempts.Add(1) == 1 {
+			return nil, fmt.Errorf("temporary failure")
+		}
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller, bee.SetParallel(1))
+
+	handler := func(name string) *process.Handler {
+		return process.NewHandlerBuilder().
+			Named("", name, "").
+			SetKind(process.ServiceKey).
+			SetAction(name, map[string]any{}).
+			Build()
+	}
+	pr := process.NewProcessBuilder().
+		Named("p1", "events process", "").
+		SetHosts("h*").
+		SetHandlers(handler("restart")).
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetAction("install", map[string]any{}).
+				SetRetries(2, 0).
+				SetNotify("restart").
+				SetFinish(handler("cleanup")).
+				Build(),
+		).
+		Build()
+
+	recorder := &eventRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{
+		"play start events process",
+		"task start install [h1 h2]",
+		"retry install@h1 1",
+		"ok install@h1",
+		"ok install@h2",
+		"finish install cleanup [h1 h2]",
+		"handler start restart [h1 h2]",
+		"ok restart@h1",
+		"ok restart@h2",
+		"play end events process ok=2",
+	}, recorder.events)
+}
+
+func TestRuntime_PlayCallbacks(t *testing.T) {
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, "h1\nh2\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "callbacks process", "").
+		SetHosts("h*").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "install", "").
+			SetAction("install", map[string]any{}).
+			Build()).
+		Build()
+
+	first, second := &resultRecorder{}, &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(first), bee.WithRunCallback(second))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, first.results, 2)
+	assert.Len(t, second.results, 2)
+}
+
+type flushRecorder struct {
+	callback.BaseCallBack
+
+	flushed int32
+}
+
+func (r *flushRecorder) Flush(ctx context.Context) error {
+	atomic.AddInt32(&r.flushed, 1)
+	return nil
+}
+
+func TestRuntime_PlayFlush(t *testing.T) {
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		return []byte(`{}`), nil
+	}
+	recorder := &flushRecorder{}
+	// the cleanups run in reverse order, it checks after the runtime stops
+	t.Cleanup(func() {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&recorder.flushed))
+	})
+	rt := newServiceRuntime(t, "h1\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "flush process", "").
+		SetHosts("h1").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "install", "").
+			SetAction("install", map[string]any{}).
+			Build()).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(&resultRecorder{}, recorder))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&recorder.flushed))
+}
+
+func TestRuntime_PlayRedact(t *testing.T) {
+	hostText := `
+h1 bee_ssh_passwd=s3cret
+`
+	var mu sync.Mutex
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		called = append(called, action+":"+string(in))
+		mu.Unlock()
+
+		switch action {
+		case "login":
+			return []byte(`{"msg": "login with s3cret", "db_passwd": "dbpw"}`), nil
+		case "token":
+			return []byte(`{"changed": true, "msg": "token t0k"}`), nil
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, hostText, caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "redact process", "").
+		SetHosts("h1").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "login", "").
+				SetAction("login", map[string]any{}).
+				SetRegister("login").
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "token", "").
+				SetAction("token", map[string]any{}).
+				SetRegister("token").
+				SetNoLog(true).
+				Build(),
+			process.NewServiceBuilder().
+				Named("s3", "print", "").
+				SetAction("print", map[string]any{"login": "{{ register.login.msg }}", "token": "{{ register.token.msg }}"}).
+				Build(),
+		).
+		Build()
+
+	recorder := &resultRecorder{}
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunCallback(recorder), bee.WithRunFilter(filter.NewRedactor()))
+	if !assert.NoError(t, err) || !assert.Len(t, recorder.results, 3) {
+		return
+	}
+
+	login := recorder.results[0]
+	assert.Equal(t, "login with ********", login.Stdout["msg"])
+	assert.Equal(t, filter.RedactedValue, login.Stdout["db_passwd"])
+
+	token := recorder.results[1]
+	assert.True(t, token.NoLog)
+	assert.Equal(t, map[string]any{"censored": stats.NoLogMessage, "changed": true}, token.Stdout)
+
+	// the registered results are redacted, the no_log task keeps the output for the later tasks
+	printed := map[string]any{}
+	if assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(called[2], "print:")), &printed)) {
+		assert.Equal(t, map[string]any{"login": "login with ********", "token": "token t0k"}, printed)
+	}
+}
+
+func TestRuntime_PlayHistory(t *testing.T) {
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		if host == "h2" {
+			return nil, errors.New("install failed")
+		}
+		return []byte(`{"changed": true}`), nil
+	}
+	rt := newServiceRuntime(t, "h1\nh2\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "history process", "").
+		SetHosts("h*").
+		SetTasks(process.NewServiceBuilder().
+			Named("s1", "install", "").
+			SetAction("install", map[string]any{}).
+			Build()).
+		Build()
+
+	report, err := rt.Play(context.TODO(), pr)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	run, err := rt.History().Get(report.RunId)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, history.KindPlay, run.Kind)
+	assert.Equal(t, "history process", run.Process)
+	assert.Equal(t, []string{"h1", "h2"}, run.Hosts)
+	assert.True(t, run.Failed())
+	assert.Len(t, run.Results, 2)
+	assert.Equal(t, int64(1), run.Stats["h1"]["changed"])
+	assert.Equal(t, int64(1), run.Stats["h2"]["failures"])
+
+	runs, err := rt.History().List(history.WithHost("h2"))
+	if assert.NoError(t, err) && assert.Len(t, runs, 1) {
+		assert.Equal(t, report.RunId, runs[0].Id)
+	}
+}
+
+func TestRuntime_PlayResume(t *testing.T) {
+	var mu sync.Mutex
+	broken := true
+	called := make([]string, 0)
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, action+"@"+host)
+
+		switch action {
+		case "install":
+			if host == "h2" && broken {
+				return nil, errors.New("install failed")
+			}
+			return []byte(`{"changed": true, "version": "1.0.` + host[1:] + `"}`), nil
+		case "configure":
+			return in, nil
+		}
+		return []byte(`{}`), nil
+	}
+	rt := newServiceRuntime(t, "h1\nh2\n", caller, bee.SetParallel(1))
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "resume process", "").
+		SetHosts("h*").
+		SetHandlers(process.NewHandlerBuilder().
+			Named("", "restart", "").
+			SetKind(process.ServiceKey).
+			SetAction("restart", map[string]any{}).
+			Build()).
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "install", "").
+				SetAction("install", map[string]any{}).
+				SetRegister("installed").
+				SetNotify("restart").
+				Build(),
+			process.NewServiceBuilder().
+				Named("s2", "configure", "").
+				SetAction("configure", map[string]any{"version": "{{ register.installed.version }}"}).
+				Build(),
+		).
+		Build()
+
+	first, err := rt.Play(context.TODO(), pr, bee.WithRunExtraVars(map[string]any{"env": "prod"}))
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"install@h1", "install@h2"}, called)
+
+	mu.Lock()
+	broken = false
+	called = called[:0]
+	mu.Unlock()
+
+	recorder := &resultRecorder{}
+	report, err := rt.Resume(context.TODO(), first.RunId, bee.WithRunCallback(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEqual(t, first.RunId, report.RunId)
+	// install succeeded on h1 in the first run
+	assert.Equal(t, []string{"install@h2", "configure@h1", "configure@h2", "restart@h1", "restart@h2"}, called)
+	assert.Equal(t, int64(1), report.Stats.Get(bexecutor.Skipped, "h1"))
+	if assert.Len(t, recorder.skipped, 1) {
+		assert.Equal(t, "h1", recorder.skipped[0].Host)
+		assert.Equal(t, "s1", recorder.skipped[0].TaskId)
+	}
+	// the registered result of h1 is restored
+	for _, result := range recorder.results {
+		if result.TaskId == "s2" {
+			assert.Equal(t, "1.0."+result.Host[1:], result.Stdout["version"])
+		}
+	}
+
+	run, err := rt.History().Get(report.RunId)
+	if assert.NoError(t, err) {
+		assert.Equal(t, first.RunId, run.Resumed)
+	}
+	cp, err := rt.History().GetCheckpoint(report.RunId)
+	if assert.NoError(t, err) {
+		// the handler is completed too
+		assert.Len(t, cp.Completed, 3)
+		assert.Subset(t, cp.Completed, []string{"s1", "s2"})
+		assert.Equal(t, "prod", cp.ExtraVars["env"])
+	}
+
+	// everything succeeded, nothing runs again
+	mu.Lock()
+	called = called[:0]
+	mu.Unlock()
+	_, err = rt.Resume(context.TODO(), report.RunId)
+	assert.NoError(t, err)
+	assert.Empty(t, called)
+
+	_, err = rt.Resume(context.TODO(), "unknown")
+	assert.ErrorIs(t, err, history.ErrNotFound)
+}
+
+func TestRuntime_PlayResumeSecrets(t *testing.T) {
+	runId := history.NewRunId(time.Now())
+
+	var rt *bee.Runtime
+	broken := true
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		if broken {
+			// the unfinished run is recorded when the play starts
+			run, err := rt.History().Get(runId)
+			if assert.NoError(t, err) {
+				assert.True(t, run.Unfinished())
+			}
+			return nil, errors.New("login failed")
+		}
+		return in, nil
+	}
+	rt = newServiceRuntime(t, "h1\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "secret process", "").
+		SetHosts("h1").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "login", "").
+				SetAction("login", map[string]any{"password": "{{ vars.db_passwd }}"}).
+				Build(),
+		).
+		Build()
+
+	vars := map[string]any{"env": "prod", "db_passwd": "s3cret"}
+	report, err := rt.Play(context.TODO(), pr, bee.WithRunId(runId),
+		bee.WithRunExtraVars(vars), bee.WithRunFilter(filter.NewRedactor()))
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Equal(t, runId, report.RunId)
+	run, err := rt.History().Get(runId)
+	if assert.NoError(t, err) {
+		assert.False(t, run.Unfinished())
+	}
+
+	cps, err := rt.History().ListCheckpoints()
+	if assert.NoError(t, err) && assert.Len(t, cps, 1) {
+		assert.Equal(t, "secret process", cps[0].Name)
+		assert.Equal(t, "prod", cps[0].ExtraVars["env"])
+		assert.Equal(t, filter.RedactedValue, cps[0].ExtraVars["db_passwd"])
+	}
+
+	broken = false
+	// the masked secrets have to be given again
+	_, err = rt.Resume(context.TODO(), runId)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "db_passwd")
+	}
+	recorder := &resultRecorder{}
+	_, err = rt.Resume(context.TODO(), runId, bee.WithRunCallback(recorder),
+		bee.WithRunExtraVars(map[string]any{"db_passwd": "s3cret"}))
+	if assert.NoError(t, err) && assert.Len(t, recorder.results, 1) {
+		assert.Equal(t, "s3cret", recorder.results[0].Stdout["password"])
+	}
+}
+
+func TestRuntime_PlayResumeProcessSecrets(t *testing.T) {
+	runId := history.NewRunId(time.Now())
+
+	broken := true
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...bee.RunOption) ([]byte, error) {
+		if broken {
+			return nil, errors.New("login failed by " + string(in))
+		}
+		return in, nil
+	}
+	rt := newServiceRuntime(t, "h1\n", caller)
+
+	pr := process.NewProcessBuilder().
+		Named("p1", "secret process", "").
+		SetHosts("h1").
+		SetTasks(
+			process.NewServiceBuilder().
+				Named("s1", "login", "").
+				SetVar("db_passwd", "s3cret").
+				SetAction("login", map[string]any{"password": "{{ vars.db_passwd }}"}).
+				Build(),
+		).
+		Build()
+
+	_, err := rt.Play(context.TODO(), pr, bee.WithRunId(runId), bee.WithRunFilter(filter.NewRedactor()))
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.NotContains(t, err.Error(), "s3cret")
+
+	cps, err := rt.History().ListCheckpoints()
+	if assert.NoError(t, err) && assert.Len(t, cps, 1) {
+		assert.NotContains(t, cps[0].Process, "s3cret")
+		assert.Contains(t, cps[0].Process, filter.RedactedValue)
+	}
+
+	broken = false
+	// the masked vars of process have to be given again
+	_, err = rt.Resume(context.TODO(), runId)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "db_passwd")
+	}
+	recorder := &resultRecorder{}
+	_, err = rt.Resume(context.TODO(), runId, bee.WithRunCallback(recorder),
+		bee.WithRunExtraVars(map[string]any{"db_passwd": "s3cret"}))
+	if assert.NoError(t, err) && assert.Len(t, recorder.results, 1) {
+		assert.Equal(t, "s3cret", recorder.results[0].Stdout["password"])
+	}
+}
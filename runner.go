@@ -0,0 +1,971 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/hashicorp/go-multierror"
+	json "github.com/json-iterator/go"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+
+	bexecutor "github.com/olive-io/bee/executor"
+	"github.com/olive-io/bee/executor/client"
+	"github.com/olive-io/bee/module"
+	"github.com/olive-io/bee/plugins/callback"
+	"github.com/olive-io/bee/plugins/filter"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+	"github.com/olive-io/bee/tengo/expr"
+	bvars "github.com/olive-io/bee/vars"
+)
+
+// runner runs the tasks of a process instance, it holds the state shares between tasks
+type runner struct {
+	rt *Runtime
+
+	opts    []RunOption
+	options *RunOptions
+	cb      callback.ICallBack
+	ft      filter.IFilter
+	// secrets are the filters of ft which mask the secrets, see filter.ISecretFilter
+	secrets []filter.ISecretFilter
+
+	// sources are the hosts of process
+	sources []string
+	// vars are the variables of process
+	vars map[string]any
+	// tasks are the tasks have run, in order
+	tasks []process.ITask
+
+	// registered are the registered outputs of tasks, it is shared by the runners of play
+	registered *registry
+
+	handlers []*process.Handler
+	// notified records the hosts of handlers which are notified, key is the name of handler
+	notified map[string][]string
+	// notifiers are the tasks which notify the handlers on hosts first, key is the name of
+	// handler and host. The handlers run as the execution users of them.
+	notifiers map[string]process.ITask
+
+	// failed are the hosts which failed a task of serial batch, they don't run the later tasks
+	failed map[string]struct{}
+	// tolerated are the errors of tasks which failed on some hosts of serial batch
+	tolerated error
+}
+
+func (rt *Runtime) newRunner(properties map[string]string, opts ...RunOption) (*runner, error) {
+	if rt.opts.check {
+		// the callers get the check mode by RunOptions
+		opts = append(opts[:len(opts):len(opts)], WithRunCheck(true))
+	}
+	options := newRunOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cb := callback.NewCallBack()
+	if options.Callback != nil {
+		cb = options.Callback
+	}
+	rt.trackCallback(cb)
+
+	ft := filter.NewFilter()
+	if options.Filter != nil {
+		ft = options.Filter
+	}
+
+	if options.Stats == nil {
+		options.Stats = bexecutor.NewStats()
+	}
+
+	var patterns []string
+	if v, ok := properties["hosts"]; ok && v != "" {
+		patterns = strings.Split(v, ",")
+	}
+
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("missing sources")
+	}
+
+	sources, err := rt.resolveHosts(patterns, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no hosts matched the sources '%s'", strings.Join(patterns, ","))
+	}
+
+	handlers, err := process.DecodeHandlers(properties["handlers"])
+	if err != nil {
+		return nil, err
+	}
+	vars, err := process.DecodeVars(properties["vars"])
+	if err != nil {
+		return nil, err
+	}
+
+	registered := options.registry
+	if registered == nil {
+		registered = newRegistry()
+	}
+
+	secrets := filter.SecretFilters(ft)
+	for _, sf := range secrets {
+		for _, host := range sources {
+			sf.AddSecrets(rt.hostSecrets(host, options)...)
+		}
+	}
+
+	r := &runner{
+		rt:         rt,
+		opts:       opts,
+		options:    options,
+		cb:         cb,
+		ft:         ft,
+		secrets:    secrets,
+		sources:    sources,
+		vars:       vars,
+		tasks:      make([]process.ITask, 0),
+		registered: registered,
+		handlers:   handlers,
+		notified:   map[string][]string{},
+		notifiers:  map[string]process.ITask{},
+		failed:     map[string]struct{}{},
+	}
+	return r, nil
+}
+
+// taskHosts returns the hosts of task, defaults to all sources
+func (r *runner) taskHosts(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return r.sources, nil
+	}
+	return r.rt.resolveHosts(patterns, r.options.Limit)
+}
+
+// active drops the hosts which failed a former task of serial batch
+func (r *runner) active(hosts []string) []string {
+	if len(r.failed) == 0 {
+		return hosts
+	}
+	out := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if _, ok := r.failed[host]; !ok {
+			out = append(out, host)
+		}
+	}
+	return out
+}
+
+// tolerate records the failed hosts of task in serial batch, the error is tolerated when
+// some hosts of task are left, they keep running the later tasks. The tolerated errors
+// are returned at the end of process, see BatchFailed.
+func (r *runner) tolerate(results []*stats.TaskResult, err error) error {
+	if err == nil || !r.options.batch {
+		return err
+	}
+
+	left := false
+	for _, result := range results {
+		if result.ErrMsg != "" {
+			r.failed[result.Host] = struct{}{}
+		} else {
+			left = true
+		}
+	}
+	if !left {
+		return err
+	}
+	r.tolerated = multierror.Append(r.tolerated, err)
+	return nil
+}
+
+// runService calls the service on hosts by the Callable of Runtime
+func (r *runner) runService(ctx context.Context, id string, sv *process.Service, headers map[string]any) (map[string]any, error) {
+	r.tasks = append(r.tasks, sv)
+	properties := map[string]any{}
+
+	hosts, err := r.taskHosts(sv.Hosts)
+	if err != nil {
+		return properties, err
+	}
+	hosts = r.active(hosts)
+
+	caller := r.rt.opts.caller
+	if caller == nil {
+		return properties, nil
+	}
+
+	r.cb.TaskOnStart(sv, hosts)
+	// the hosts which succeeded in the resumed run are skipped
+	hosts, restored := r.restore(sv, hosts)
+	// the hosts failed to evaluate the condition are reported, the task keeps running on the others
+	hosts, reported, wErr := r.evalWhen(ctx, sv, sv.When, sv.Vars, hosts)
+
+	ropts := append(r.opts[:len(r.opts):len(r.opts)], WithMetadata(headers))
+	spec := &taskSpec{
+		INamedTask: sv,
+		vars:       sv.Vars,
+		args:       sv.Args,
+		loop:       sv.Loop,
+		loopVar:    sv.GetLoopVar(),
+		retries:    sv.Retries,
+		delay:      sv.Delay,
+		noLog:      sv.NoLog,
+	}
+	outs, errs := r.rt.runOnHosts(ctx, hosts, sv.Forks, func(ctx context.Context, host string) ([]byte, error) {
+		return r.invoke(ctx, host, spec, func(ctx context.Context, args map[string]any) ([]byte, error) {
+			in, _ := json.Marshal(args)
+			return caller(ctx, host, sv.Action, in, ropts...)
+		})
+	})
+
+	results := make([]*stats.TaskResult, len(hosts))
+	for i, host := range hosts {
+		results[i] = &stats.TaskResult{
+			Host:   host,
+			Task:   sv.Name,
+			TaskId: sv.Id,
+			NoLog:  sv.NoLog,
+		}
+	}
+	properties, err = r.collect(id, results, outs, errs)
+	done := append(reported, results...)
+	r.checkpoint(sv.Id, done)
+	for _, result := range restored {
+		properties[result.Host] = result.Stdout
+	}
+	r.notifyRestored(sv, restored, sv.Notify)
+	r.notify(sv, results, sv.Notify)
+	r.register(sv.Register, append(done, restored...))
+	if wErr != nil {
+		err = multierror.Append(wErr, err)
+	}
+
+	return properties, r.tolerate(append(done, restored...), err)
+}
+
+// runScript executes the module of task on hosts
+func (r *runner) runScript(ctx context.Context, id string, task *process.Task, headers map[string]any) (map[string]any, error) {
+	r.tasks = append(r.tasks, task)
+	properties := map[string]any{}
+
+	if task.Action == process.FlushHandlersAction {
+		return properties, r.flushHandlers(ctx)
+	}
+
+	hosts, err := r.taskHosts(task.Hosts)
+	if err != nil {
+		return properties, err
+	}
+	hosts = r.active(hosts)
+
+	r.cb.TaskOnStart(task, hosts)
+	// the hosts which succeeded in the resumed run are skipped
+	hosts, restored := r.restore(task, hosts)
+	// the hosts failed to evaluate the condition are reported, the task keeps running on the others
+	hosts, reported, wErr := r.evalWhen(ctx, task, task.When, task.Vars, hosts)
+
+	ropts := append(r.opts[:len(r.opts):len(r.opts)], WithMetadata(headers))
+	ropts = append(ropts, privilegeOptions(task)...)
+	if task.NoLog {
+		ropts = append(ropts, WithRunNoLog(true))
+	}
+	taskOptions := newRunOptions()
+	for _, opt := range ropts {
+		opt(taskOptions)
+	}
+	spec := &taskSpec{
+		INamedTask: task,
+		vars:       task.Vars,
+		args:       task.Args,
+		loop:       task.Loop,
+		loopVar:    task.GetLoopVar(),
+		retries:    task.Retries,
+		delay:      task.Delay,
+		noLog:      task.NoLog,
+	}
+	outs, errs := r.rt.runOnHosts(ctx, hosts, task.Forks, func(ctx context.Context, host string) ([]byte, error) {
+		return r.invoke(ctx, host, spec, func(ctx context.Context, args map[string]any) ([]byte, error) {
+			return r.rt.execute(ctx, host, buildShell(task.Action, args), ropts...)
+		})
+	})
+
+	results := make([]*stats.TaskResult, len(hosts))
+	for i, host := range hosts {
+		results[i] = &stats.TaskResult{
+			Host:   host,
+			Task:   task.Name,
+			TaskId: task.Id,
+			User:   r.rt.executionUser(host, taskOptions),
+			NoLog:  task.NoLog,
+		}
+	}
+	properties, err = r.collect(id, results, outs, errs)
+	done := append(reported, results...)
+	r.checkpoint(task.Id, done)
+	for _, result := range restored {
+		properties[result.Host] = result.Stdout
+	}
+	r.notifyRestored(task, restored, task.Notify)
+	r.notify(task, results, task.Notify)
+	r.register(task.Register, append(done, restored...))
+	if wErr != nil {
+		err = multierror.Append(wErr, err)
+	}
+
+	return properties, r.tolerate(append(done, restored...), err)
+}
+
+// taskSpec is the common part of process.Task and process.Service which runs on a host
+type taskSpec struct {
+	process.INamedTask
+
+	vars    map[string]any
+	args    map[string]any
+	loop    any
+	loopVar string
+	retries int
+	delay   int
+	noLog   bool
+}
+
+// invoke renders the args of task on the host and calls fn with them,
+// fn is called once per item when the task has loop.
+func (r *runner) invoke(ctx context.Context, host string, spec *taskSpec, fn func(ctx context.Context, args map[string]any) ([]byte, error)) ([]byte, error) {
+	if spec.retries > 0 {
+		call := fn
+		fn = func(ctx context.Context, args map[string]any) ([]byte, error) {
+			return r.retry(ctx, host, spec, func(ctx context.Context) ([]byte, error) {
+				return call(ctx, args)
+			})
+		}
+	}
+
+	if spec.loop != nil {
+		return r.runLoop(ctx, host, spec, fn)
+	}
+
+	args, err := r.renderArgs(ctx, spec.GetName(), host, spec.vars, spec.args)
+	if err != nil {
+		return nil, err
+	}
+	return fn(ctx, args)
+}
+
+// retry calls fn until it succeeds, at most 1+retries times. Each rerun is
+// reported through callback and waits the delay of task.
+func (r *runner) retry(ctx context.Context, host string, spec *taskSpec, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		data, err := fn(ctx)
+		if err == nil || attempt > spec.retries || ctx.Err() != nil {
+			return data, err
+		}
+
+		result := &stats.TaskResult{
+			Host:     host,
+			Task:     spec.GetName(),
+			TaskId:   spec.GetId(),
+			ErrMsg:   r.redact(err.Error()),
+			Attempts: attempt,
+			NoLog:    spec.noLog,
+		}
+		r.cb.RunnerOnRetry(result.Censored())
+		select {
+		case <-ctx.Done():
+			return data, err
+		case <-time.After(time.Duration(spec.delay) * time.Second):
+		}
+	}
+}
+
+// runLoop runs the task once per item of loop on the host, the item is rendered into
+// the args as the loop variable. The outputs of items are aggregated into "results".
+func (r *runner) runLoop(ctx context.Context, host string, spec *taskSpec, fn func(ctx context.Context, args map[string]any) ([]byte, error)) ([]byte, error) {
+	vars, err := r.hostVars(ctx, host, spec.vars)
+	if err != nil {
+		return nil, fmt.Errorf("task '%s' on host '%s': %w", spec.GetName(), host, err)
+	}
+	items, err := loopItems(ctx, spec.loop, vars)
+	if err != nil {
+		return nil, fmt.Errorf("task '%s' on host '%s': loop: %w", spec.GetName(), host, err)
+	}
+
+	changed := false
+	results := make([]any, 0, len(items))
+	var errs error
+	for _, item := range items {
+		vars[spec.loopVar] = item
+
+		stdout := map[string]any{}
+		args, err := expr.Render(ctx, spec.args, vars)
+		if err == nil {
+			var data []byte
+			data, err = fn(ctx, args.(map[string]any))
+			if err == nil {
+				err = json.Unmarshal(data, &stdout)
+			}
+		}
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("task '%s' on host '%s': item '%v': %w", spec.GetName(), host, item, err))
+			stdout = map[string]any{"failed": true, "msg": err.Error()}
+		}
+
+		changed = changed || stats.IsChanged(stdout)
+		stdout[spec.loopVar] = item
+		results = append(results, stdout)
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"changed": changed,
+		"results": results,
+	})
+	return data, errs
+}
+
+// loopItems returns the items of loop, the loop is a list or an expression resolving to a list
+func loopItems(ctx context.Context, loop any, vars map[string]any) ([]any, error) {
+	var value any
+	var err error
+	switch tt := loop.(type) {
+	case string:
+		if expr.IsTemplate(tt) {
+			value, err = expr.Render(ctx, tt, vars)
+		} else {
+			value, err = expr.Eval(ctx, tt, vars)
+		}
+	default:
+		value, err = expr.Render(ctx, loop, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expect a list, got '%v'", value)
+	}
+	return items, nil
+}
+
+// hostVars returns the variables of host which the expressions evaluate against, see
+// vars.VariableManager HostVars for the precedence. The variables are accessible by name
+// or from "vars", the registered outputs of tasks are in "register".
+func (r *runner) hostVars(ctx context.Context, host string, taskVars map[string]any) (map[string]any, error) {
+	vars, err := r.rt.variables.HostVars(ctx, host, bvars.Scope{
+		Process: r.vars,
+		Task:    taskVars,
+		Extra:   r.options.ExtraVars,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	registered := r.registered.outputs(host)
+
+	out := map[string]any{}
+	for key, value := range vars {
+		out[key] = value
+	}
+	out["vars"] = vars
+	out["register"] = registered
+
+	for _, sf := range r.secrets {
+		sf.AddSecretVars(vars)
+	}
+	return out, nil
+}
+
+// renderArgs renders the args on the host, the error names the task and host
+func (r *runner) renderArgs(ctx context.Context, name, host string, taskVars, args map[string]any) (map[string]any, error) {
+	vars, err := r.hostVars(ctx, host, taskVars)
+	if err == nil {
+		var out any
+		out, err = expr.Render(ctx, args, vars)
+		if err == nil {
+			return out.(map[string]any), nil
+		}
+	}
+	return nil, fmt.Errorf("task '%s' on host '%s': %w", name, host, err)
+}
+
+// evalWhen evaluates the condition of task on hosts, returns the hosts which the condition is true.
+// The other hosts are reported as skipped, and the hosts failed to evaluate are reported as failed,
+// the results of them are returned too.
+func (r *runner) evalWhen(ctx context.Context, task process.INamedTask, when string, taskVars map[string]any, hosts []string) ([]string, []*stats.TaskResult, error) {
+	if when == "" {
+		return hosts, nil, nil
+	}
+
+	var errs error
+	matched := make([]string, 0, len(hosts))
+	reported := make([]*stats.TaskResult, 0)
+	for _, host := range hosts {
+		result := &stats.TaskResult{
+			Host:   host,
+			Task:   task.GetName(),
+			TaskId: task.GetId(),
+		}
+
+		vars, err := r.hostVars(ctx, host, taskVars)
+		ok := false
+		if err == nil {
+			ok, err = expr.EvalBool(ctx, when, vars)
+		}
+		if err != nil {
+			err = fmt.Errorf("task '%s' on host '%s': when: %v", task.GetName(), host, err)
+			errs = multierror.Append(errs, err)
+			r.fail(result, err)
+			reported = append(reported, result)
+			continue
+		}
+		if ok {
+			matched = append(matched, host)
+			continue
+		}
+
+		result.Stdout = map[string]any{
+			"skipped":     true,
+			"skip_reason": "conditional result was false",
+		}
+		r.skip(result)
+		reported = append(reported, result)
+	}
+
+	return matched, reported, errs
+}
+
+// collect parses the outputs of hosts into results and reports them through callback,
+// returns the stdout of hosts, key is the host.
+func (r *runner) collect(id string, results []*stats.TaskResult, outs [][]byte, errs []error) (map[string]any, error) {
+	properties := map[string]any{}
+
+	var aErr error
+	for i, result := range results {
+		data, err := outs[i], errs[i]
+		if err != nil {
+			aErr = multierror.Append(aErr, r.redactErr(err))
+			if len(data) > 0 {
+				// keeps the partial output, e.g. the results of loop
+				_ = json.Unmarshal(data, &result.Stdout)
+				for _, sf := range r.secrets {
+					result.Stdout = sf.OnPostTaskStdout(id, result.Stdout)
+				}
+			}
+			r.fail(result, err)
+			continue
+		}
+
+		stdout := map[string]any{}
+		if err = json.Unmarshal(data, &stdout); err != nil {
+			aErr = multierror.Append(aErr, r.redactErr(err))
+			r.fail(result, err)
+			continue
+		}
+
+		stdout = r.ft.OnPostTaskStdout(id, stdout)
+		result.Stdout = stdout
+		result.Changed = stats.IsChanged(stdout)
+		properties[result.Host] = stdout
+
+		if stats.IsSkipped(stdout) {
+			// e.g. the module doesn't support check mode
+			r.skip(result)
+			continue
+		}
+		r.ok(result)
+	}
+
+	return properties, aErr
+}
+
+// ok counts the succeeded host and reports it through callback
+func (r *runner) ok(result *stats.TaskResult) {
+	as := r.options.Stats
+	as.Increment(bexecutor.Ok, result.Host)
+	if result.Changed {
+		as.Increment(bexecutor.Changed, result.Host)
+	}
+	r.cb.RunnerOnOk(result.Censored())
+}
+
+// fail counts the failed host and reports it through callback,
+// the host is unreachable if the connection fails.
+func (r *runner) fail(result *stats.TaskResult, err error) {
+	result.ErrMsg = r.redact(err.Error())
+	var ce *module.CommandErr
+	if errors.As(err, &ce) {
+		result.Stderr = r.redact(string(ce.Stderr))
+	}
+	if errors.Is(err, client.ErrConnect) {
+		r.options.Stats.Increment(bexecutor.Dark, result.Host)
+		r.cb.RunnerOnUnreachable(result.Censored())
+		return
+	}
+	r.options.Stats.Increment(bexecutor.Failures, result.Host)
+	r.cb.RunnerOkFailed(result.Censored())
+}
+
+// redact masks the secrets in text by the secret filters
+func (r *runner) redact(text string) string {
+	for _, sf := range r.secrets {
+		text = sf.Redact(text)
+	}
+	return text
+}
+
+// redactErr returns the error which the secrets in text are masked, the cause is kept for errors.Is
+func (r *runner) redactErr(err error) error {
+	text := r.redact(err.Error())
+	if text == err.Error() {
+		return err
+	}
+	return &redactedError{cause: err, text: text}
+}
+
+type redactedError struct {
+	cause error
+	text  string
+}
+
+func (e *redactedError) Error() string { return e.text }
+
+func (e *redactedError) Unwrap() error { return e.cause }
+
+// skip counts the skipped host and reports it through callback
+func (r *runner) skip(result *stats.TaskResult) {
+	r.options.Stats.Increment(bexecutor.Skipped, result.Host)
+	r.cb.RunnerOnSkipped(result.Censored())
+}
+
+// rescue counts the failed hosts which are handled by the catch of task
+func (r *runner) rescue(hosts []string) {
+	as := r.options.Stats
+	for _, host := range hosts {
+		if as.Get(bexecutor.Failures, host) > 0 {
+			as.Increment(bexecutor.Rescued, host)
+		}
+	}
+}
+
+// register stores the results of hosts by the name, the later tasks refer to them as "register.<name>"
+func (r *runner) register(name string, results []*stats.TaskResult) {
+	if name == "" {
+		return
+	}
+
+	for _, result := range results {
+		value := map[string]any{}
+		for key, item := range result.Stdout {
+			value[key] = item
+		}
+		value["changed"] = result.Changed
+		if result.ErrMsg != "" {
+			value["failed"] = true
+			value["msg"] = result.ErrMsg
+		}
+
+		r.registered.set(result.Host, name, value)
+	}
+}
+
+// registry keeps the registered outputs of tasks by host, it is shared by
+// the batches and segments of play, see withRunRegistry.
+type registry struct {
+	mu    sync.RWMutex
+	hosts map[string]map[string]any
+}
+
+func newRegistry() *registry {
+	return &registry{hosts: map[string]map[string]any{}}
+}
+
+func (rg *registry) set(host, name string, value any) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	registered, ok := rg.hosts[host]
+	if !ok {
+		registered = map[string]any{}
+		rg.hosts[host] = registered
+	}
+	registered[name] = value
+}
+
+// outputs returns a copy of the registered outputs of host
+func (rg *registry) outputs(host string) map[string]any {
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+	out := map[string]any{}
+	for key, value := range rg.hosts[host] {
+		out[key] = value
+	}
+	return out
+}
+
+// restore returns the hosts which the task didn't succeed on in the resumed run and the
+// results of the others, the others are reported as skipped.
+func (r *runner) restore(task process.INamedTask, hosts []string) ([]string, []*stats.TaskResult) {
+	ckp := r.options.checkpoint
+	if ckp == nil {
+		return hosts, nil
+	}
+
+	pending := make([]string, 0, len(hosts))
+	restored := make([]*stats.TaskResult, 0)
+	for _, host := range hosts {
+		result, ok := ckp.succeeded(task.GetId(), host)
+		if !ok {
+			pending = append(pending, host)
+			continue
+		}
+		restored = append(restored, result)
+		r.skip(&stats.TaskResult{
+			Host:   host,
+			Task:   task.GetName(),
+			TaskId: task.GetId(),
+			Stdout: map[string]any{
+				"skipped":     true,
+				"skip_reason": "completed in run " + ckp.prev.RunId,
+			},
+			NoLog: result.NoLog,
+		})
+	}
+	if len(restored) > 0 && len(pending) == 0 {
+		r.rt.Logger().Info("skip completed task",
+			zap.String("name", task.GetName()),
+			zap.String("id", task.GetId()))
+	}
+	return pending, restored
+}
+
+// checkpoint records the outcomes of task in the checkpoint of play
+func (r *runner) checkpoint(id string, results []*stats.TaskResult) {
+	ckp := r.options.checkpoint
+	if ckp == nil || len(results) == 0 {
+		return
+	}
+	ckp.record(id, results)
+}
+
+// notify records the handlers notified by the changed results of task
+func (r *runner) notify(task process.ITask, results []*stats.TaskResult, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	for _, result := range results {
+		if !result.Changed {
+			continue
+		}
+		for _, name := range names {
+			hosts := r.notified[name]
+			if !lo.Contains[string](hosts, result.Host) {
+				r.notified[name] = append(hosts, result.Host)
+				r.notifiers[notifierKey(name, result.Host)] = task
+			}
+		}
+	}
+}
+
+func notifierKey(name, host string) string {
+	return name + "\x00" + host
+}
+
+// notifyRestored records the handlers notified by the restored results, the handlers
+// which succeeded on the host in the resumed run aren't notified again.
+func (r *runner) notifyRestored(task process.ITask, results []*stats.TaskResult, names []string) {
+	if len(results) == 0 {
+		return
+	}
+
+	for _, name := range names {
+		var handler *process.Handler
+		for _, item := range r.handlers {
+			if item.Name == name || item.Id == name {
+				handler = item
+				break
+			}
+		}
+
+		pending := make([]*stats.TaskResult, 0, len(results))
+		for _, result := range results {
+			if handler != nil {
+				if _, ok := r.options.checkpoint.succeeded(handler.Id, result.Host); ok {
+					continue
+				}
+			}
+			pending = append(pending, result)
+		}
+		r.notify(task, pending, []string{name})
+	}
+}
+
+// notifiedGroup are the hosts of handler which are notified by the tasks of the same execution user
+type notifiedGroup struct {
+	task  process.ITask
+	hosts []string
+}
+
+// flushHandlers runs the notified handlers in the order of definition, each of them runs once per host.
+// The handler runs as the execution user of the task which notifies it, e.g. sudo.
+func (r *runner) flushHandlers(ctx context.Context) error {
+	lg := r.rt.Logger()
+	if len(r.notified) == 0 {
+		return nil
+	}
+
+	var errs error
+	for _, handler := range r.handlers {
+		hosts := make([]string, 0)
+		groups := make([]*notifiedGroup, 0)
+		for _, name := range []string{handler.Name, handler.Id} {
+			if name == "" {
+				continue
+			}
+			for _, host := range r.active(r.notified[name]) {
+				if lo.Contains[string](hosts, host) {
+					continue
+				}
+				hosts = append(hosts, host)
+
+				task := r.notifiers[notifierKey(name, host)]
+				group, ok := lo.Find(groups, func(item *notifiedGroup) bool {
+					return privilegeKey(item.task) == privilegeKey(task)
+				})
+				if !ok {
+					group = &notifiedGroup{task: task}
+					groups = append(groups, group)
+				}
+				group.hosts = append(group.hosts, host)
+			}
+			delete(r.notified, name)
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+
+		lg.Info("run notified handler",
+			zap.Stringer("handler", handler),
+			zap.Strings("hosts", hosts))
+		r.cb.HandlerOnStart(handler, hosts)
+		for _, group := range groups {
+			if err := r.runHandler(ctx, handler, group.hosts, r.taskOptions(group.task)...); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	for name, hosts := range r.notified {
+		lg.Warn("notified handler not found",
+			zap.String("handler", name),
+			zap.Strings("hosts", hosts))
+	}
+	r.notified = map[string][]string{}
+	r.notifiers = map[string]process.ITask{}
+
+	return errs
+}
+
+// runHandler runs the handler on hosts, reports the results through callback like normal tasks
+func (r *runner) runHandler(ctx context.Context, handler *process.Handler, hosts []string, opts ...RunOption) error {
+	call := r.handlerCall(handler, opts...)
+	if call == nil {
+		return nil
+	}
+
+	outs, errs := r.rt.runOnHosts(ctx, hosts, 0, call)
+	results := make([]*stats.TaskResult, len(hosts))
+	for i, host := range hosts {
+		results[i] = &stats.TaskResult{
+			Host:   host,
+			Task:   handler.Name,
+			TaskId: handler.Id,
+		}
+	}
+	_, err := r.collect(handler.Id, results, outs, errs)
+	r.checkpoint(handler.Id, results)
+	return err
+}
+
+// taskOptions returns the options of run with the execution user of task, e.g. the
+// catch and finish handlers of task keep the check mode and callbacks of run.
+func (r *runner) taskOptions(task process.ITask) []RunOption {
+	return append(r.opts[:len(r.opts):len(r.opts)], privilegeOptions(task)...)
+}
+
+// handle runs the catch or finish handler of task on hosts one by one
+func (r *runner) handle(ctx context.Context, hosts []string, handler *process.Handler, opts ...RunOption) error {
+	call := r.handlerCall(handler, opts...)
+	if call == nil {
+		return nil
+	}
+
+	for _, host := range hosts {
+		if _, err := call(ctx, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerCall returns the function which runs the handler on a host, the args of handler
+// are rendered on each host. It returns nil if the handler can't run.
+func (r *runner) handlerCall(handler *process.Handler, opts ...RunOption) func(ctx context.Context, host string) ([]byte, error) {
+	switch handler.Kind {
+	case process.ServiceKey:
+		caller := r.rt.opts.caller
+		if caller == nil {
+			return nil
+		}
+		return func(ctx context.Context, host string) ([]byte, error) {
+			args, err := r.renderArgs(ctx, handler.Name, host, nil, handler.Args)
+			if err != nil {
+				return nil, err
+			}
+			in, _ := json.Marshal(args)
+			return caller(ctx, host, handler.Action, in, opts...)
+		}
+	case process.TaskKey, "":
+		return func(ctx context.Context, host string) ([]byte, error) {
+			args, err := r.renderArgs(ctx, handler.Name, host, nil, handler.Args)
+			if err != nil {
+				return nil, err
+			}
+			return r.rt.execute(ctx, host, buildShell(handler.Action, args), opts...)
+		}
+	}
+	return nil
+}
+
+// buildShell builds the command line of module, likes "ping data=hello"
+func buildShell(action string, args map[string]any) string {
+	items := make([]string, 0, len(args)+1)
+	items = append(items, action)
+	for name, arg := range args {
+		value, _ := json.Marshal(arg)
+		items = append(items, name+"="+strings.ReplaceAll(string(value), "\"", ""))
+	}
+	return strings.Join(items, " ")
+}
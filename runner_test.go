@@ -0,0 +1,111 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/process"
+	"github.com/olive-io/bee/stats"
+	"github.com/olive-io/bee/vars"
+)
+
+func TestRunner_FlushHandlersPrivilege(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"repl", "modules"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataloader := parser.NewDataLoader()
+	if err := dataloader.ParseString("h1\nh2\nh3\n"); err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := inv.NewInventoryManager(dataloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := vars.NewVariablesManager(dataloader, inventory)
+
+	var mu sync.Mutex
+	called := map[string]*RunOptions{}
+	caller := func(ctx context.Context, host, action string, in []byte, opts ...RunOption) ([]byte, error) {
+		options := &RunOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		mu.Lock()
+		called[action+"@"+host] = options
+		mu.Unlock()
+		return []byte(`{}`), nil
+	}
+	rt, err := NewRuntime(inventory, variables, dataloader, SetDir(dir), SetLogger(zap.NewNop()), SetCaller(caller))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = rt.Stop() }()
+
+	restart := process.NewHandlerBuilder().
+		Named("", "restart", "").
+		SetKind(process.ServiceKey).
+		SetAction("restart", map[string]any{}).
+		Build()
+	properties := map[string]string{
+		"hosts":    "h*",
+		"handlers": process.EncodeHandlers([]*process.Handler{restart}),
+	}
+	r, err := rt.newRunner(properties, WithRunCheck(true))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	install := process.NewTaskBuilder().Named("t1", "install", "").Build()
+	install.Sudo = true
+	install.SudoUser = "admin"
+	configure := process.NewTaskBuilder().Named("t2", "configure", "").Build()
+
+	r.notify(install, []*stats.TaskResult{{Host: "h1", Changed: true}}, []string{"restart"})
+	r.notify(configure, []*stats.TaskResult{{Host: "h1", Changed: true}, {Host: "h2", Changed: true}}, []string{"restart"})
+	if !assert.NoError(t, r.flushHandlers(context.TODO())) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Contains(t, called, "restart@h1") {
+		// the first task which notifies the handler on host wins
+		assert.True(t, called["restart@h1"].Become)
+		assert.Equal(t, "admin", called["restart@h1"].BecomeUser)
+		assert.True(t, called["restart@h1"].Check)
+	}
+	if assert.Contains(t, called, "restart@h2") {
+		assert.False(t, called["restart@h2"].Become)
+		assert.True(t, called["restart@h2"].Check)
+	}
+	assert.NotContains(t, called, "restart@h3")
+}
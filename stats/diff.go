@@ -0,0 +1,67 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package stats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// DiffKey is the field of module output, the value is an unified diff of the changed content
+	DiffKey = "diff"
+)
+
+// GetDiffs returns the diffs in the stdout of module, includes the diffs of loop results
+func GetDiffs(stdout map[string]any) []string {
+	diffs := make([]string, 0)
+	if text, ok := stdout[DiffKey].(string); ok && text != "" {
+		diffs = append(diffs, text)
+	}
+
+	results, _ := stdout["results"].([]any)
+	for _, item := range results {
+		if sub, ok := item.(map[string]any); ok {
+			diffs = append(diffs, GetDiffs(sub)...)
+		}
+	}
+	return diffs
+}
+
+// RenderDiff writes the diffs of the result under the header of host,
+// writes nothing if the result has no diff.
+func RenderDiff(w io.Writer, result *TaskResult) error {
+	diffs := GetDiffs(result.Stdout)
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "diff: [%s]\n", result.Host); err != nil {
+		return err
+	}
+	for _, text := range diffs {
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
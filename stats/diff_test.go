@@ -0,0 +1,54 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package stats
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderDiff(t *testing.T) {
+	result := &TaskResult{
+		Host: "h1",
+		Stdout: map[string]any{
+			"changed": true,
+			"results": []any{
+				map[string]any{"diff": "--- a\n+++ a\n@@ -1 +1 @@\n-1\n+2\n"},
+				map[string]any{"changed": false},
+				map[string]any{"diff": "--- b\n+++ b\n@@ -1 +1 @@\n-x\n+y"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := RenderDiff(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	expect := "diff: [h1]\n--- a\n+++ a\n@@ -1 +1 @@\n-1\n+2\n--- b\n+++ b\n@@ -1 +1 @@\n-x\n+y\n"
+	if out.String() != expect {
+		t.Fatalf("RenderDiff() = %q, want %q", out.String(), expect)
+	}
+
+	out.Reset()
+	if err := RenderDiff(&out, &TaskResult{Host: "h2", Stdout: map[string]any{}}); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expect empty output, got %q", out.String())
+	}
+}
@@ -17,8 +17,68 @@
 
 package stats
 
+import "strconv"
+
 type TaskResult struct {
-	Host   string         `json:"host"`
-	Stdout map[string]any `json:"stdout"`
-	ErrMsg string         `json:"err_msg"`
+	Host    string         `json:"host"`
+	Task    string         `json:"task"`
+	TaskId  string         `json:"task_id"`
+	User    string         `json:"user,omitempty"`
+	Changed bool           `json:"changed"`
+	Stdout  map[string]any `json:"stdout"`
+	ErrMsg  string         `json:"err_msg"`
+	// Stderr is the error output of the failed module
+	Stderr string `json:"stderr,omitempty"`
+	// Attempts is the number of runs of the task on the host, it is set when the task retries
+	Attempts int `json:"attempts,omitempty"`
+	// NoLog is set when the task hides the output, see Censored
+	NoLog bool `json:"no_log,omitempty"`
+}
+
+// NoLogMessage replaces the output of result which NoLog is set
+const NoLogMessage = "the output has been hidden due to the fact that 'no_log: true' was specified for this result"
+
+// Censored returns the copy of result without the output when NoLog is set, the result itself otherwise
+func (r *TaskResult) Censored() *TaskResult {
+	if !r.NoLog {
+		return r
+	}
+
+	out := *r
+	out.Stdout = map[string]any{"censored": NoLogMessage}
+	// keeps the status of result
+	for _, key := range []string{"changed", "skipped"} {
+		if value, ok := r.Stdout[key]; ok {
+			out.Stdout[key] = value
+		}
+	}
+	if out.ErrMsg != "" {
+		out.ErrMsg = NoLogMessage
+	}
+	out.Stderr = ""
+	return &out
+}
+
+// IsChanged reports whether the stdout of module contains "changed: true"
+func IsChanged(stdout map[string]any) bool {
+	switch tt := stdout["changed"].(type) {
+	case bool:
+		return tt
+	case string:
+		changed, _ := strconv.ParseBool(tt)
+		return changed
+	}
+	return false
+}
+
+// IsSkipped reports whether the stdout of module contains "skipped: true"
+func IsSkipped(stdout map[string]any) bool {
+	switch tt := stdout["skipped"].(type) {
+	case bool:
+		return tt
+	case string:
+		skipped, _ := strconv.ParseBool(tt)
+		return skipped
+	}
+	return false
 }
@@ -41,4 +41,65 @@ func TestTaskStdout(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if !IsChanged(r.Stdout) {
+		t.Fatal("expect changed stdout")
+	}
+}
+
+func TestIsChanged(t *testing.T) {
+	cases := []struct {
+		stdout  map[string]any
+		changed bool
+	}{
+		{nil, false},
+		{map[string]any{"changed": false}, false},
+		{map[string]any{"changed": true}, true},
+		{map[string]any{"changed": "true"}, true},
+		{map[string]any{"changed": "no"}, false},
+		{map[string]any{"changed": 1}, false},
+	}
+	for _, c := range cases {
+		if got := IsChanged(c.stdout); got != c.changed {
+			t.Fatalf("IsChanged(%v) = %v, want %v", c.stdout, got, c.changed)
+		}
+	}
+}
+
+func TestIsSkipped(t *testing.T) {
+	cases := []struct {
+		stdout  map[string]any
+		skipped bool
+	}{
+		{nil, false},
+		{map[string]any{"skipped": false}, false},
+		{map[string]any{"skipped": true}, true},
+		{map[string]any{"skipped": "true"}, true},
+		{map[string]any{"changed": true}, false},
+	}
+	for _, c := range cases {
+		if got := IsSkipped(c.stdout); got != c.skipped {
+			t.Fatalf("IsSkipped(%v) = %v, want %v", c.stdout, got, c.skipped)
+		}
+	}
+}
+
+func TestTaskResult_Censored(t *testing.T) {
+	r := &TaskResult{Host: "h1", Stdout: map[string]any{"changed": true, "token": "secret"}, Stderr: "secret"}
+	if r.Censored() != r {
+		t.Fatal("expect the result itself without no_log")
+	}
+
+	r.NoLog = true
+	r.ErrMsg = "login secret failed"
+	out := r.Censored()
+	if out.Stdout["censored"] != NoLogMessage || out.Stdout["changed"] != true || out.Stdout["token"] != nil {
+		t.Fatalf("unexpected censored stdout %v", out.Stdout)
+	}
+	if out.ErrMsg != NoLogMessage || out.Stderr != "" {
+		t.Fatalf("unexpected censored error '%s', '%s'", out.ErrMsg, out.Stderr)
+	}
+	if r.Stdout["token"] != "secret" {
+		t.Fatal("expect the result unchanged")
+	}
 }
@@ -0,0 +1,139 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+var (
+	Importable tengo.Importable = NewBee()
+)
+
+// ImportBee is the module about the runtime of bee, e.g. the check mode
+type ImportBee struct {
+	Attrs map[string]tengo.Object
+}
+
+func NewBee() *ImportBee {
+	b := &ImportBee{}
+	attrs := map[string]tengo.Object{}
+	attrs["check_mode"] = &tengo.UserFunction{Name: "check_mode", Value: checkMode}
+	attrs["diff_mode"] = &tengo.UserFunction{Name: "diff_mode", Value: diffMode}
+	attrs["diff"] = &tengo.UserFunction{Name: "diff", Value: diff}
+	b.Attrs = attrs
+
+	return b
+}
+
+// Import returns an immutable map for the module.
+func (b *ImportBee) Import(moduleName string) (interface{}, error) {
+	return b.AsImmutableMap(moduleName), nil
+}
+
+func (b *ImportBee) Version() string {
+	return "v1.0.0"
+}
+
+// AsImmutableMap converts builtin module into an immutable map.
+func (b *ImportBee) AsImmutableMap(name string) *tengo.ImmutableMap {
+	attrs := make(map[string]tengo.Object, len(b.Attrs))
+	for k, v := range b.Attrs {
+		attrs[k] = v.Copy()
+	}
+	attrs["__module_name__"] = &tengo.String{Value: name}
+	return &tengo.ImmutableMap{Value: attrs}
+}
+
+// checkMode reports whether the module runs in check mode, the module
+// should report what it would change without changing it.
+func checkMode(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return tengo.FromInterface(isEnabled(client.EnvCheckMode))
+}
+
+// diffMode reports whether the module should return the diff of changed content
+func diffMode(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	return tengo.FromInterface(isEnabled(client.EnvDiffMode))
+}
+
+// diff returns the unified diff of before and after content: diff(before, after[, from[, to]]),
+// the names of file default to "before" and "after".
+func diff(args ...tengo.Object) (tengo.Object, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+
+	names := []string{"before", "after", "before", "after"}
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		text, ok := tengo.ToString(arg)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     names[i],
+				Expected: "string(compatible)",
+				Found:    arg.TypeName(),
+			}
+		}
+		texts[i] = text
+	}
+	copy(names[2:], texts[2:])
+
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(texts[0]),
+		B:        splitLines(texts[1]),
+		FromFile: names[2],
+		ToFile:   names[3],
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tengo.String{Value: text}, nil
+}
+
+// splitLines splits the text into lines, each line ends with "\n"
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += "\n"
+	return lines
+}
+
+func isEnabled(key string) bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(key))
+	return enabled
+}
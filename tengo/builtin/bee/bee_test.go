@@ -0,0 +1,67 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package bee
+
+import (
+	"testing"
+
+	"github.com/d5/tengo/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/olive-io/bee/executor/client"
+)
+
+func runScript(t *testing.T, text string) *tengo.Compiled {
+	modules := tengo.NewModuleMap()
+	modules.Add("bee", Importable)
+
+	script := tengo.NewScript([]byte(`bee := import("bee")` + "\n" + text))
+	script.SetImports(modules)
+	compiled, err := script.Run()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return compiled
+}
+
+func TestCheckMode(t *testing.T) {
+	t.Setenv(client.EnvCheckMode, "")
+	assert.False(t, runScript(t, `check := bee.check_mode()`).Get("check").Bool())
+
+	t.Setenv(client.EnvCheckMode, "true")
+	assert.True(t, runScript(t, `check := bee.check_mode()`).Get("check").Bool())
+}
+
+func TestDiffMode(t *testing.T) {
+	t.Setenv(client.EnvDiffMode, "")
+	assert.False(t, runScript(t, `d := bee.diff_mode()`).Get("d").Bool())
+
+	t.Setenv(client.EnvDiffMode, "1")
+	assert.True(t, runScript(t, `d := bee.diff_mode()`).Get("d").Bool())
+}
+
+func TestDiff(t *testing.T) {
+	compiled := runScript(t, `d := bee.diff("a\nb\n", "a\nc\n", "/etc/old", "/etc/new")`)
+	assert.Equal(t, "--- /etc/old\n+++ /etc/new\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n", compiled.Get("d").String())
+
+	compiled = runScript(t, `d := bee.diff("a", "a")`)
+	assert.Equal(t, "", compiled.Get("d").String())
+
+	compiled = runScript(t, `d := bee.diff("", "a\n")`)
+	assert.Equal(t, "--- before\n+++ after\n@@ -0,0 +1 @@\n+a\n", compiled.Get("d").String())
+}
@@ -20,6 +20,7 @@ package builtin
 import (
 	"github.com/d5/tengo/v2"
 
+	"github.com/olive-io/bee/tengo/builtin/bee"
 	"github.com/olive-io/bee/tengo/builtin/exec"
 	"github.com/olive-io/bee/tengo/builtin/filepath"
 	"github.com/olive-io/bee/tengo/builtin/flag"
@@ -33,4 +34,5 @@ func init() {
 	BuiltinMap.Add("flag", flag.Importable)
 	BuiltinMap.Add("trace", trace.Importable)
 	BuiltinMap.Add("exec", exec.Importable)
+	BuiltinMap.Add("bee", bee.Importable)
 }
@@ -0,0 +1,132 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package expr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+	json "github.com/json-iterator/go"
+)
+
+const resultName = "__expr_result__"
+
+// unresolvedPattern matches the compile error of tengo for undefined variable
+var unresolvedPattern = regexp.MustCompile(`unresolved reference '([^']+)'`)
+
+// UndefinedError is returned when the expression refers to an undefined variable
+type UndefinedError struct {
+	Name string
+}
+
+func (e *UndefinedError) Error() string {
+	return fmt.Sprintf("undefined variable '%s'", e.Name)
+}
+
+// modules are the stdlib modules which could be imported by expression,
+// the modules have side effect on the controller (os) are excluded.
+var modules = []string{"math", "text", "times", "rand", "fmt", "json", "base64", "hex", "enum"}
+
+// Eval evaluates the tengo expression with the variables, each of variables
+// is defined as a global variable of the expression.
+func Eval(ctx context.Context, expression string, variables map[string]any) (any, error) {
+	object, err := run(ctx, expression, variables)
+	if err != nil {
+		return nil, err
+	}
+	return tengo.ToInterface(object), nil
+}
+
+// EvalBool evaluates the tengo expression as a condition, the result follows the truthiness of tengo
+func EvalBool(ctx context.Context, expression string, variables map[string]any) (bool, error) {
+	object, err := run(ctx, expression, variables)
+	if err != nil {
+		return false, err
+	}
+	return !object.IsFalsy(), nil
+}
+
+func run(ctx context.Context, expression string, variables map[string]any) (tengo.Object, error) {
+	script := tengo.NewScript([]byte(resultName + " := (" + expression + ")"))
+	script.SetImports(stdlib.GetModuleMap(modules...))
+	for name, value := range variables {
+		object, err := toObject(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %v", name, err)
+		}
+		if err = script.Add(name, object); err != nil {
+			return nil, fmt.Errorf("variable '%s': %v", name, err)
+		}
+	}
+
+	compiled, err := script.RunContext(ctx)
+	if err != nil {
+		if matches := unresolvedPattern.FindStringSubmatch(err.Error()); len(matches) > 1 {
+			return nil, &UndefinedError{Name: matches[1]}
+		}
+		return nil, fmt.Errorf("evaluate '%s': %v", expression, err)
+	}
+	return compiled.Get(resultName).Object(), nil
+}
+
+// toObject converts the value to tengo.Object, the value which isn't supported
+// by tengo directly (e.g. map[string]string) is converted by the json encoding.
+func toObject(value any) (tengo.Object, error) {
+	object, err := tengo.FromInterface(normalize(value))
+	if err == nil {
+		return object, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err = json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return tengo.FromInterface(normalize(out))
+}
+
+// normalize converts the integral float64 (decoded from json) to int64,
+// so that they are equal to the integers in expression.
+func normalize(value any) any {
+	switch tt := value.(type) {
+	case float64:
+		if tt == math.Trunc(tt) && math.Abs(tt) < 1<<53 {
+			return int64(tt)
+		}
+	case map[string]any:
+		out := make(map[string]any, len(tt))
+		for key, item := range tt {
+			out[key] = normalize(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(tt))
+		for i, item := range tt {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+	return value
+}
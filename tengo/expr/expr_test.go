@@ -0,0 +1,109 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package expr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalBool(t *testing.T) {
+	variables := map[string]any{
+		"os":   "linux",
+		"port": 22,
+		"size": float64(1024),
+		"vars": map[string]string{"bee_arch": "amd64"},
+		"register": map[string]any{
+			"check": map[string]any{"changed": true, "items": []any{"a", "b"}},
+		},
+	}
+
+	cases := []struct {
+		expression string
+		result     bool
+	}{
+		{`os == "linux"`, true},
+		{`os == "windows" || port > 20`, true},
+		{`vars.bee_arch == "arm64"`, false},
+		{`register.check.changed`, true},
+		{`len(register.check.items) == 2`, true},
+		{`import("text").has_prefix(os, "lin")`, true},
+		{`vars.missing`, false},
+		{`size == 1024`, true},
+	}
+	for _, c := range cases {
+		result, err := EvalBool(context.TODO(), c.expression, variables)
+		if assert.NoError(t, err, c.expression) {
+			assert.Equal(t, c.result, result, c.expression)
+		}
+	}
+
+	_, err := EvalBool(context.TODO(), `os ==`, variables)
+	assert.Error(t, err)
+	_, err = EvalBool(context.TODO(), `undefined_var`, variables)
+	assert.Error(t, err)
+	_, err = EvalBool(context.TODO(), `import("os").getenv("HOME")`, variables)
+	assert.Error(t, err)
+}
+
+func TestEval(t *testing.T) {
+	value, err := Eval(context.TODO(), `items + ["c"]`, map[string]any{"items": []any{"a", "b"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []any{"a", "b", "c"}, value)
+	}
+}
+
+func TestRender(t *testing.T) {
+	variables := map[string]any{
+		"item":     "nginx",
+		"version":  2,
+		"packages": []any{"a", "b"},
+	}
+
+	value, err := Render(context.TODO(), map[string]any{
+		"name":     "{{ item }}",
+		"pkg":      "{{item}}-{{ version }}.0",
+		"packages": "{{ packages }}",
+		"count":    1,
+		"nested":   []any{"{{ version + 1 }}", "plain"},
+	}, variables)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]any{
+		"name":     "nginx",
+		"pkg":      "nginx-2.0",
+		"packages": []any{"a", "b"},
+		"count":    1,
+		"nested":   []any{int64(3), "plain"},
+	}, value)
+
+	var ue *UndefinedError
+	_, err = Render(context.TODO(), "{{ missing }}", variables)
+	if assert.ErrorAs(t, err, &ue) {
+		assert.Equal(t, "missing", ue.Name)
+	}
+	_, err = Render(context.TODO(), "a {{ vars.missing }}", map[string]any{"vars": map[string]any{}})
+	if assert.ErrorAs(t, err, &ue) {
+		assert.Equal(t, "vars.missing", ue.Name)
+	}
+	assert.True(t, IsTemplate("a {{ b }}"))
+	assert.False(t, IsTemplate("a }} {{ b"))
+}
@@ -0,0 +1,117 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package expr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	leftDelim  = "{{"
+	rightDelim = "}}"
+)
+
+// Render replaces the templates "{{ expression }}" in value with the results of expressions.
+// The maps and lists are rendered recursively. The string which contains a single template
+// only is replaced by the result as it is, e.g. a list, otherwise the results are formatted
+// into the string. It returns *UndefinedError when the result of any expression is undefined.
+func Render(ctx context.Context, value any, variables map[string]any) (any, error) {
+	switch tt := value.(type) {
+	case string:
+		return renderString(ctx, tt, variables)
+	case map[string]any:
+		out := make(map[string]any, len(tt))
+		for key, item := range tt {
+			rendered, err := Render(ctx, item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(tt))
+		for i, item := range tt {
+			rendered, err := Render(ctx, item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+// IsTemplate reports whether the text contains template
+func IsTemplate(text string) bool {
+	start := strings.Index(text, leftDelim)
+	return start >= 0 && strings.Contains(text[start:], rightDelim)
+}
+
+func renderString(ctx context.Context, text string, variables map[string]any) (any, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, leftDelim) && strings.HasSuffix(trimmed, rightDelim) &&
+		strings.Count(trimmed, leftDelim) == 1 {
+		expression := trimmed[len(leftDelim) : len(trimmed)-len(rightDelim)]
+		return evalDefined(ctx, expression, variables)
+	}
+
+	var sb strings.Builder
+	rest := text
+	for {
+		start := strings.Index(rest, leftDelim)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], rightDelim)
+		if end < 0 {
+			break
+		}
+		end += start
+
+		sb.WriteString(rest[:start])
+		value, err := evalDefined(ctx, rest[start+len(leftDelim):end], variables)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(fmt.Sprintf("%v", value))
+		rest = rest[end+len(rightDelim):]
+	}
+	sb.WriteString(rest)
+
+	return sb.String(), nil
+}
+
+// evalDefined evaluates the expression, the undefined result is an error
+func evalDefined(ctx context.Context, expression string, variables map[string]any) (any, error) {
+	value, err := Eval(ctx, expression, variables)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, &UndefinedError{Name: strings.TrimSpace(expression)}
+	}
+	return value, nil
+}
@@ -19,11 +19,18 @@ package tengo
 
 import "github.com/d5/tengo/v2"
 
+// ReplRevision is the revision of the builtin modules of repl, e.g. the "bee" module.
+// It is bumped when the builtin modules change, the remote repl which is older than
+// the controller is uploaded again.
+const ReplRevision = 1
+
 type TengoV struct {
 	Version   string `json:"version"`
 	GoVersion string `json:"go-version"`
 	Goos      string `json:"os"`
 	Platform  string `json:"platform"`
+	// Revision is ReplRevision of repl, it is 0 for the repl without it
+	Revision int `json:"revision"`
 }
 
 type BeeImportable interface {
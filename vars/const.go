@@ -26,8 +26,20 @@ const (
 	BeeSSHPrivateKeyVars = "bee_ssh_private_key"
 	BeeSSHPassphraseVars = "bee_ssh_passphrase"
 
+	// BeeSSHHostKeyCheckingVars is one of strict, accept-new and off
+	BeeSSHHostKeyCheckingVars = "bee_ssh_host_key_checking"
+	BeeSSHKnownHostsVars      = "bee_ssh_known_hosts"
+	// BeeSSHHostKeyVars pins the keys of host in authorized_keys format, separated by ','
+	BeeSSHHostKeyVars = "bee_ssh_host_key"
+	// BeeSSHProxyJumpVars are the bastions of host, e.g. user@bastion:22[,next]
+	BeeSSHProxyJumpVars = "bee_ssh_proxy_jump"
+
 	BeeWMPasswdVars = "bee_winrm_passwd"
 
+	BeeBecomeMethodVars = "bee_become_method"
+	BeeBecomeUserVars   = "bee_become_user"
+	BeeBecomePasswdVars = "bee_become_passwd"
+
 	BeePlatformVars = "bee_platform"
 	BeeArchVars     = "bee_arch"
 	BeeHome         = "bee_home"
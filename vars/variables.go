@@ -18,10 +18,30 @@
 package vars
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	inv "github.com/olive-io/bee/inventory"
 	"github.com/olive-io/bee/parser"
+	"github.com/olive-io/bee/tengo/expr"
 )
 
+// maxRenderDepth limits the passes of rendering the inventory vars which refer to each other
+const maxRenderDepth = 8
+
+// Scope is the variables of a run, they take precedence over the inventory vars
+type Scope struct {
+	// Process are the vars of process
+	Process map[string]any
+	// Task are the vars of task
+	Task map[string]any
+	// Extra are the extra vars of run, e.g. --extra-vars of command line
+	Extra map[string]any
+}
+
 type VariableManager struct {
 	loader    *parser.DataLoader
 	inventory *inv.Manager
@@ -57,14 +77,124 @@ func (vm *VariableManager) Reconcile() {
 	vm.hostVariables = hostVariables
 }
 
+// MustGetHostDefaultValue returns the rendered inventory var of host, see RenderHostVars.
+// The defaultV is returned if the var isn't set or it fails to render.
 func (vm *VariableManager) MustGetHostDefaultValue(host, name, defaultV string) string {
 	hv, ok := vm.hostVariables[host]
 	if !ok {
 		return defaultV
 	}
+	if _, ok = hv[name]; !ok {
+		return defaultV
+	}
+	hv, err := RenderHostVars(context.Background(), host, hv)
+	if err != nil {
+		return defaultV
+	}
 	value, ok := hv[name]
 	if !ok {
 		return defaultV
 	}
 	return value
 }
+
+// HostVars returns the variables of host in the scope. The precedence from low to high is:
+//
+//  1. inventory group vars
+//  2. inventory host vars
+//  3. process vars
+//  4. task vars
+//  5. extra vars
+//
+// The templates "{{ expression }}" in the string inventory vars are rendered against the result.
+// The inventory vars which refer to undefined variables are left out, the error is deferred
+// until they are used, e.g. "undefined variable 'log_dir'" when the task renders "{{ log_dir }}".
+func (vm *VariableManager) HostVars(ctx context.Context, host string, scope Scope) (map[string]any, error) {
+	out := map[string]any{}
+	inventoryVars := vm.hostVariables[host]
+	for key, value := range inventoryVars {
+		out[key] = value
+	}
+	for _, item := range []map[string]any{scope.Process, scope.Task, scope.Extra} {
+		for key, value := range item {
+			out[key] = value
+		}
+	}
+
+	if _, err := renderTemplates(ctx, host, inventoryVars, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RenderHostVars renders the templates in the inventory vars of host against each other,
+// e.g. the connection vars like bee_host = "{{ ip }}" before the host is dialed.
+// The connection vars (prefixed with "bee_") which refer to undefined variables are errors,
+// the others are left out like HostVars.
+func RenderHostVars(ctx context.Context, host string, hostVars map[string]string) (map[string]string, error) {
+	out := make(map[string]any, len(hostVars))
+	for key, value := range hostVars {
+		out[key] = value
+	}
+
+	undefined, err := renderTemplates(ctx, host, hostVars, out)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(undefined))
+	for key := range undefined {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, "bee_") {
+			return nil, fmt.Errorf("host '%s': var '%s': %w", host, key, undefined[key])
+		}
+	}
+
+	rendered := make(map[string]string, len(out))
+	for key, value := range out {
+		if text, ok := value.(string); ok {
+			rendered[key] = text
+		} else {
+			rendered[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return rendered, nil
+}
+
+// renderTemplates renders the inventory vars in out until no template remains, the inventory vars
+// may refer to each other. The vars which refer to undefined variables are removed from out and
+// returned with the errors of them.
+func renderTemplates(ctx context.Context, host string, inventoryVars map[string]string, out map[string]any) (map[string]error, error) {
+	undefined := map[string]error{}
+	for depth := 0; ; depth++ {
+		pending := make([]string, 0)
+		for key := range inventoryVars {
+			if text, ok := out[key].(string); ok && expr.IsTemplate(text) {
+				pending = append(pending, key)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if depth >= maxRenderDepth {
+			return nil, fmt.Errorf("host '%s': too many nested templates in vars %v", host, pending)
+		}
+
+		for _, key := range pending {
+			value, err := expr.Render(ctx, out[key], out)
+			if err != nil {
+				var ue *expr.UndefinedError
+				if errors.As(err, &ue) {
+					undefined[key] = err
+					delete(out, key)
+					continue
+				}
+				return nil, fmt.Errorf("host '%s': var '%s': %w", host, key, err)
+			}
+			out[key] = value
+		}
+	}
+	return undefined, nil
+}
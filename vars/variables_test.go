@@ -0,0 +1,119 @@
+/*
+   Copyright 2024 The bee Authors
+
+   This library is free software; you can redistribute it and/or
+   modify it under the terms of the GNU Lesser General Public
+   License as published by the Free Software Foundation; either
+   version 2.1 of the License, or (at your option) any later version.
+
+   This library is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+   Lesser General Public License for more details.
+
+   You should have received a copy of the GNU Lesser General Public
+   License along with this library;
+*/
+
+package vars
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	inv "github.com/olive-io/bee/inventory"
+	"github.com/olive-io/bee/parser"
+)
+
+const inventoryText = `
+h1 level=host app_dir={{base_dir}}/app
+h2
+
+[web]
+h1
+h2
+
+[web:vars]
+level=group
+base_dir=/opt
+log_dir={{app_dir}}/logs
+`
+
+func newTestManager(t *testing.T) *VariableManager {
+	dataloader := parser.NewDataLoader()
+	if err := dataloader.ParseString(inventoryText); err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := inv.NewInventoryManager(dataloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewVariablesManager(dataloader, inventory)
+}
+
+func TestVariableManager_HostVars(t *testing.T) {
+	vm := newTestManager(t)
+	ctx := context.TODO()
+
+	vars, err := vm.HostVars(ctx, "h1", Scope{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "host", vars["level"])
+	assert.Equal(t, "/opt/app", vars["app_dir"])
+	assert.Equal(t, "/opt/app/logs", vars["log_dir"])
+
+	scope := Scope{
+		Process: map[string]any{"level": "process", "base_dir": "/srv", "a": 1},
+		Task:    map[string]any{"level": "task", "b": 2},
+		Extra:   map[string]any{"level": "extra"},
+	}
+	vars, err = vm.HostVars(ctx, "h1", scope)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "extra", vars["level"])
+	assert.Equal(t, "/srv/app/logs", vars["log_dir"])
+	assert.Equal(t, 1, vars["a"])
+	assert.Equal(t, 2, vars["b"])
+
+	scope.Extra = nil
+	vars, _ = vm.HostVars(ctx, "h1", scope)
+	assert.Equal(t, "task", vars["level"])
+	scope.Task = nil
+	vars, _ = vm.HostVars(ctx, "h1", scope)
+	assert.Equal(t, "process", vars["level"])
+
+	// app_dir is undefined on h2, log_dir is left out until it is used
+	vars, err = vm.HostVars(ctx, "h2", Scope{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "group", vars["level"])
+		assert.NotContains(t, vars, "log_dir")
+	}
+}
+
+func TestRenderHostVars(t *testing.T) {
+	ctx := context.TODO()
+	hostVars := map[string]string{
+		"ip":        "10.0.0.1",
+		BeeHostVars: "{{ ip }}",
+		BeeUserVars: "{{ user }}",
+		"log_dir":   "{{ app_dir }}/logs",
+	}
+	_, err := RenderHostVars(ctx, "h1", hostVars)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "var 'bee_user'")
+		assert.Contains(t, err.Error(), "undefined variable 'user'")
+	}
+
+	hostVars["user"] = "admin"
+	vars, err := RenderHostVars(ctx, "h1", hostVars)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "10.0.0.1", vars[BeeHostVars])
+	assert.Equal(t, "admin", vars[BeeUserVars])
+	assert.NotContains(t, vars, "log_dir")
+}